@@ -0,0 +1,113 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is matched, via errors.Is, by the errors returned from endpoints
+// wrapped with the Timeout middleware when the timeout elapses.
+var ErrTimeout = errors.New("endpoint timeout")
+
+// TimeoutError is returned by endpoints wrapped with the Timeout middleware
+// when the request didn't complete within the configured timeout. It matches
+// both ErrTimeout and context.DeadlineExceeded via errors.Is.
+type TimeoutError struct {
+	Timeout time.Duration // the configured timeout
+	Err     error         // error returned by the wrapped endpoint, if any
+}
+
+// Error implements the error interface.
+func (e TimeoutError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v after %v: %v", ErrTimeout, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("%v after %v", ErrTimeout, e.Timeout)
+}
+
+// Is reports whether target is ErrTimeout or context.DeadlineExceeded.
+func (e TimeoutError) Is(target error) bool {
+	return target == ErrTimeout || target == context.DeadlineExceeded
+}
+
+// Unwrap returns the error returned by the wrapped endpoint, if any.
+func (e TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutOption sets an optional parameter for the Timeout middleware.
+type TimeoutOption func(*timeoutMiddleware)
+
+// TimeoutMargin shrinks the deadline of the context passed to the wrapped
+// endpoint by the given margin. Use it when the wrapped endpoint calls a
+// downstream service, e.g. via transport/http.Client or transport/grpc.Client,
+// so the downstream call gives up, and its deadline propagates, before the
+// caller's own deadline expires. By default, no margin is applied.
+func TimeoutMargin(margin time.Duration) TimeoutOption {
+	return func(m *timeoutMiddleware) { m.margin = margin }
+}
+
+type timeoutMiddleware struct {
+	timeout time.Duration
+	margin  time.Duration
+}
+
+// Timeout returns an endpoint.Middleware that bounds the execution time of
+// the wrapped endpoint. If the endpoint doesn't return within timeout, the
+// context passed to it is canceled and a TimeoutError is returned without
+// waiting any further. Deadlines already present on the incoming context are
+// honored; if the incoming context ends first, its error is returned as-is. A
+// timeout of zero or less applies no timeout of its own, which is useful in
+// combination with TimeoutMargin.
+func Timeout(timeout time.Duration, options ...TimeoutOption) Middleware {
+	m := &timeoutMiddleware{timeout: timeout}
+	for _, option := range options {
+		option(m)
+	}
+	return m.middleware
+}
+
+func (m *timeoutMiddleware) middleware(next Endpoint) Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		var (
+			waitctx = ctx
+			cancel  = context.CancelFunc(func() {})
+		)
+		if m.timeout > 0 {
+			waitctx, cancel = context.WithTimeout(ctx, m.timeout)
+		}
+		defer cancel()
+
+		nextctx := waitctx
+		if deadline, ok := waitctx.Deadline(); ok && m.margin > 0 {
+			var nextcancel context.CancelFunc
+			nextctx, nextcancel = context.WithDeadline(waitctx, deadline.Add(-m.margin))
+			defer nextcancel()
+		}
+
+		type result struct {
+			response interface{}
+			err      error
+		}
+		results := make(chan result, 1)
+		go func() {
+			response, err := next(nextctx, request)
+			results <- result{response, err}
+		}()
+
+		select {
+		case r := <-results:
+			if r.err != nil && ctx.Err() == nil && errors.Is(r.err, context.DeadlineExceeded) && nextctx.Err() != nil {
+				return r.response, TimeoutError{Timeout: m.timeout, Err: r.err}
+			}
+			return r.response, r.err
+		case <-waitctx.Done():
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return nil, TimeoutError{Timeout: m.timeout}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestTimeout(t *testing.T) {
+	var (
+		block   = make(chan struct{})
+		slow    = func(ctx context.Context, _ interface{}) (interface{}, error) { <-block; return struct{}{}, nil }
+		timeout = 10 * time.Millisecond
+	)
+	defer close(block)
+
+	_, err := endpoint.Timeout(timeout)(slow)(context.Background(), struct{}{})
+	if !errors.Is(err, endpoint.ErrTimeout) {
+		t.Errorf("want %v, have %v", endpoint.ErrTimeout, err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("want %v, have %v", context.DeadlineExceeded, err)
+	}
+	var timeoutErr endpoint.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("want TimeoutError, have %T", err)
+	}
+	if want, have := timeout, timeoutErr.Timeout; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutSuccess(t *testing.T) {
+	if _, err := endpoint.Timeout(time.Second)(endpoint.Nop)(context.Background(), struct{}{}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestTimeoutParentCanceled(t *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		wait        = func(ctx context.Context, _ interface{}) (interface{}, error) { <-ctx.Done(); return nil, ctx.Err() }
+	)
+	cancel()
+	_, err := endpoint.Timeout(time.Second)(wait)(ctx, struct{}{})
+	if want, have := context.Canceled, err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutMargin(t *testing.T) {
+	var (
+		margin   = time.Second
+		timeout  = time.Minute
+		deadline time.Time
+		record   = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			deadline, _ = ctx.Deadline()
+			return struct{}{}, nil
+		}
+	)
+
+	before := time.Now()
+	if _, err := endpoint.Timeout(timeout, endpoint.TimeoutMargin(margin))(record)(context.Background(), struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if deadline.IsZero() {
+		t.Fatal("wrapped endpoint got no deadline")
+	}
+	if deadline.Before(before.Add(timeout-margin)) || deadline.After(time.Now().Add(timeout-margin)) {
+		t.Errorf("deadline %v not shrunk by margin %v", deadline, margin)
+	}
+
+	// The margin alone, without a timeout, shrinks inherited deadlines.
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	parent, _ := ctx.Deadline()
+	if _, err := endpoint.Timeout(0, endpoint.TimeoutMargin(margin))(record)(ctx, struct{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := parent.Add(-margin), deadline; !want.Equal(have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestTimeoutMarginDownstreamDeadline(t *testing.T) {
+	var (
+		wait = func(ctx context.Context, _ interface{}) (interface{}, error) { <-ctx.Done(); return nil, ctx.Err() }
+		e    = endpoint.Timeout(50*time.Millisecond, endpoint.TimeoutMargin(40*time.Millisecond))(wait)
+	)
+	_, err := e(context.Background(), struct{}{})
+	if !errors.Is(err, endpoint.ErrTimeout) {
+		t.Errorf("want %v, have %v", endpoint.ErrTimeout, err)
+	}
+}
@@ -0,0 +1,96 @@
+package endpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Typed is a type-safe variant of Endpoint. Request and response types are
+// fixed at compile time, so implementations don't need to perform their own
+// type assertions. A Typed endpoint can be converted to an Endpoint with its
+// Endpoint method, and an Endpoint can be converted to a Typed endpoint with
+// FromEndpoint.
+type Typed[Req, Resp any] func(ctx context.Context, request Req) (response Resp, err error)
+
+// Endpoint returns an untyped Endpoint that invokes the Typed endpoint. If the
+// request passed to the returned Endpoint isn't of type Req, the Typed
+// endpoint isn't invoked and an error wrapping ErrUnexpectedType is returned.
+// A nil request is passed to the Typed endpoint as the zero value of Req.
+func (e Typed[Req, Resp]) Endpoint() Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, err := Assert[Req](request)
+		if err != nil {
+			return nil, err
+		}
+		return e(ctx, req)
+	}
+}
+
+// FromEndpoint returns a Typed endpoint that invokes the untyped Endpoint e.
+// If e returns a non-nil response that isn't of type Resp, an error wrapping
+// ErrUnexpectedType is returned. A nil response is returned as the zero value
+// of Resp. Errors returned by e are returned as-is, along with the response.
+func FromEndpoint[Req, Resp any](e Endpoint) Typed[Req, Resp] {
+	return func(ctx context.Context, request Req) (Resp, error) {
+		response, err := e(ctx, request)
+		resp, assertErr := Assert[Resp](response)
+		if err != nil {
+			return resp, err
+		}
+		return resp, assertErr
+	}
+}
+
+// TypedMiddleware is a chainable behavior modifier for Typed endpoints.
+type TypedMiddleware[Req, Resp any] func(Typed[Req, Resp]) Typed[Req, Resp]
+
+// TypedChain is a helper function for composing typed middlewares. Requests
+// will traverse them in the order they're declared. That is, the first
+// middleware is treated as the outermost middleware.
+func TypedChain[Req, Resp any](outer TypedMiddleware[Req, Resp], others ...TypedMiddleware[Req, Resp]) TypedMiddleware[Req, Resp] {
+	return func(next Typed[Req, Resp]) Typed[Req, Resp] {
+		for i := len(others) - 1; i >= 0; i-- { // reverse
+			next = others[i](next)
+		}
+		return outer(next)
+	}
+}
+
+// Typify adapts an untyped Middleware, e.g. one of the circuit breakers or
+// rate limiters provided by Go kit, for use with Typed endpoints.
+func Typify[Req, Resp any](m Middleware) TypedMiddleware[Req, Resp] {
+	return func(next Typed[Req, Resp]) Typed[Req, Resp] {
+		return FromEndpoint[Req, Resp](m(next.Endpoint()))
+	}
+}
+
+// Untyped adapts a TypedMiddleware for use with untyped Endpoints. Requests
+// and responses flowing through the returned Middleware are subject to the
+// same type checks as Typed.Endpoint and FromEndpoint.
+func (m TypedMiddleware[Req, Resp]) Untyped() Middleware {
+	return func(next Endpoint) Endpoint {
+		return m(FromEndpoint[Req, Resp](next)).Endpoint()
+	}
+}
+
+// ErrUnexpectedType is wrapped by the errors returned when converting between
+// Typed and untyped endpoints, if a request or response has the wrong type.
+// Use errors.Is to check for it.
+var ErrUnexpectedType = errors.New("unexpected type")
+
+// Assert converts v to type T. A nil v yields the zero value of T. If v
+// is of another type, an error wrapping ErrUnexpectedType is returned. It's
+// intended to help adapt typed functions, e.g. decoders and encoders, to the
+// untyped signatures used by transports.
+func Assert[T any](v interface{}) (T, error) {
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+	var zero T
+	if v == nil {
+		return zero, nil
+	}
+	return zero, fmt.Errorf("%w: want %s, have %T", ErrUnexpectedType, reflect.TypeOf((*T)(nil)).Elem(), v)
+}
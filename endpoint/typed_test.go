@@ -0,0 +1,85 @@
+package endpoint_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+func TestTypedEndpoint(t *testing.T) {
+	var e endpoint.Typed[int, string] = func(_ context.Context, request int) (string, error) {
+		return strconv.Itoa(request), nil
+	}
+
+	response, err := e.Endpoint()(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "42", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	if _, err := e.Endpoint()(context.Background(), "42"); !errors.Is(err, endpoint.ErrUnexpectedType) {
+		t.Errorf("want %v, have %v", endpoint.ErrUnexpectedType, err)
+	}
+}
+
+func TestFromEndpoint(t *testing.T) {
+	untyped := func(_ context.Context, request interface{}) (interface{}, error) {
+		switch request.(int) {
+		case 0:
+			return nil, nil
+		case 1:
+			return "one", nil
+		default:
+			return 2, nil
+		}
+	}
+	e := endpoint.FromEndpoint[int, string](untyped)
+
+	for _, testcase := range []struct {
+		request int
+		want    string
+		wantErr error
+	}{
+		{0, "", nil},
+		{1, "one", nil},
+		{2, "", endpoint.ErrUnexpectedType},
+	} {
+		have, err := e(context.Background(), testcase.request)
+		if !errors.Is(err, testcase.wantErr) {
+			t.Errorf("%d: want error %v, have %v", testcase.request, testcase.wantErr, err)
+		}
+		if want := testcase.want; want != have {
+			t.Errorf("%d: want %q, have %q", testcase.request, want, have)
+		}
+	}
+}
+
+func TestTypedChain(t *testing.T) {
+	var order []string
+	annotate := func(s string) endpoint.TypedMiddleware[string, string] {
+		return func(next endpoint.Typed[string, string]) endpoint.Typed[string, string] {
+			return func(ctx context.Context, request string) (string, error) {
+				order = append(order, s)
+				return next(ctx, request+s)
+			}
+		}
+	}
+	echo := func(_ context.Context, request string) (string, error) { return request, nil }
+
+	e := endpoint.TypedChain(annotate("a"), annotate("b"), endpoint.Typify[string, string](annotate("c").Untyped()))(echo)
+	response, err := e(context.Background(), ">")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := ">abc", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 3, len(order); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
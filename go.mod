@@ -1,6 +1,6 @@
 module github.com/go-kit/kit
 
-go 1.17
+go 1.18
 
 require (
 	github.com/VividCortex/gohistogram v1.0.0
@@ -12,6 +12,7 @@ require (
 	github.com/go-kit/log v0.2.0
 	github.com/go-zookeeper/zk v1.0.2
 	github.com/golang-jwt/jwt/v4 v4.5.1
+	github.com/gorilla/websocket v1.5.3
 	github.com/hashicorp/consul/api v1.14.0
 	github.com/hudl/fargo v1.4.0
 	github.com/influxdata/influxdb1-client v0.0.0-20200827194710-b269163b24ab
@@ -30,8 +31,10 @@ require (
 	go.etcd.io/etcd/client/v3 v3.5.0
 	go.opencensus.io v0.23.0
 	go.uber.org/zap v1.19.1
+	go.yaml.in/yaml/v2 v2.4.4
 	golang.org/x/sync v0.11.0
 	golang.org/x/time v0.3.0
+	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1
 	google.golang.org/grpc v1.56.3
 	google.golang.org/protobuf v1.33.0
 )
@@ -87,7 +90,6 @@ require (
 	golang.org/x/net v0.36.0 // indirect
 	golang.org/x/sys v0.30.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
-	google.golang.org/genproto v0.0.0-20230410155749-daa745c078e1 // indirect
 	gopkg.in/gcfg.v1 v1.2.3 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
 )
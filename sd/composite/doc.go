@@ -0,0 +1,4 @@
+// Package composite provides an Instancer that merges the instances of
+// several other Instancers, e.g. to discover a service registered in both
+// ZooKeeper and Consul during a migration between them.
+package composite
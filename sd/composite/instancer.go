@@ -0,0 +1,207 @@
+package composite
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+// Source is an Instancer merged by a composite Instancer.
+type Source struct {
+	sd.Instancer
+
+	// Priority is the priority of this source. Lower values are preferred,
+	// as with sd.InstanceRecord. The records of preferred sources are
+	// preferred over the records of other sources, whatever their own
+	// priority. When several sources yield the same instance, the record of
+	// the preferred source is published.
+	Priority int
+}
+
+// Instancer merges and deduplicates the instances yielded by several sources.
+// The priorities of the sources and the priorities of their records are
+// composed, so records are preferred by source first.
+//
+// When a source fails, the Instancer keeps publishing the instances it last
+// yielded, like sd.Endpointer does by default. Errors are only passed through
+// when all the sources that sent any event are failing.
+type Instancer struct {
+	cache   *instance.Cache
+	sources []Source
+	order   []int // indexes of sources, by priority
+	chans   []chan sd.Event
+	logger  log.Logger
+	quitc   chan struct{}
+
+	mtx    sync.Mutex
+	states []state // indexed like sources
+}
+
+type state struct {
+	reported  bool // whether the source sent any event yet
+	instances []string
+	records   []sd.InstanceRecord
+	err       error
+}
+
+// NewInstancer returns an Instancer that publishes the instances of all the
+// sources. Stopping the returned Instancer doesn't stop the sources.
+func NewInstancer(sources []Source, logger log.Logger) *Instancer {
+	in := &Instancer{
+		cache:   instance.NewCache(),
+		sources: sources,
+		order:   make([]int, len(sources)),
+		chans:   make([]chan sd.Event, len(sources)),
+		logger:  logger,
+		quitc:   make(chan struct{}),
+		states:  make([]state, len(sources)),
+	}
+	for i := range in.order {
+		in.order[i] = i
+	}
+	sort.SliceStable(in.order, func(i, j int) bool {
+		return sources[in.order[i]].Priority < sources[in.order[j]].Priority
+	})
+
+	// Sources usually send their current state upon registration, but may
+	// do so later, so they're all read by the loops. Until a source sends
+	// its first event, it yields no instances.
+	for i, source := range sources {
+		in.chans[i] = make(chan sd.Event, 1)
+		source.Register(in.chans[i])
+	}
+	for i := range sources {
+		go in.loop(i)
+	}
+	return in
+}
+
+// Stop terminates the Instancer. It doesn't stop the sources.
+func (in *Instancer) Stop() {
+	for i, source := range in.sources {
+		source.Deregister(in.chans[i])
+	}
+	close(in.quitc)
+}
+
+// Register implements Instancer.
+func (in *Instancer) Register(ch chan<- sd.Event) {
+	in.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (in *Instancer) Deregister(ch chan<- sd.Event) {
+	in.cache.Deregister(ch)
+}
+
+func (in *Instancer) loop(i int) {
+	for {
+		select {
+		case event := <-in.chans[i]:
+			in.mtx.Lock()
+			in.states[i] = in.state(i, event)
+			in.cache.Update(in.event())
+			in.mtx.Unlock()
+		case <-in.quitc:
+			return
+		}
+	}
+}
+
+// state returns the state of the i-th source after the event. Failing
+// sources keep their last instances.
+func (in *Instancer) state(i int, event sd.Event) state {
+	if event.Err != nil {
+		in.logger.Log("source", i, "err", event.Err)
+		s := in.states[i]
+		s.reported = true
+		s.err = event.Err
+		return s
+	}
+	return state{reported: true, instances: event.Instances, records: event.Records}
+}
+
+// event merges the states of the sources. It must be called with the mutex
+// held.
+//
+// The records are ordered by the priority of their source first, and then by
+// their own priority, and the Priority of the published records is their rank
+// in that order. For example, the records of a source with priority 0 and
+// priorities 10 and 20 are published with priorities 0 and 1, and the records
+// of a source with priority 1 and priority 10 with priority 2.
+func (in *Instancer) event() sd.Event {
+	var (
+		instances = []string{}
+		records   = []sd.InstanceRecord{}
+		ranks     = []rank{} // indexed like records
+		seen      = map[string]bool{}
+		reported  = 0
+		failing   = 0
+		err       error
+	)
+	for _, i := range in.order {
+		s := in.states[i]
+		if !s.reported {
+			continue
+		}
+		reported++
+		if s.err != nil {
+			failing++
+			if err == nil {
+				err = s.err
+			}
+		}
+		byInstance := make(map[string]sd.InstanceRecord, len(s.records))
+		for _, record := range s.records {
+			byInstance[record.Instance] = record
+		}
+		for _, instance := range s.instances {
+			if seen[instance] {
+				continue
+			}
+			seen[instance] = true
+
+			record, ok := byInstance[instance]
+			if !ok {
+				record = sd.InstanceRecord{Instance: instance}
+			}
+
+			instances = append(instances, instance)
+			records = append(records, record)
+			ranks = append(ranks, rank{in.sources[i].Priority, record.Priority})
+		}
+	}
+	if failing > 0 && failing == reported {
+		return sd.Event{Err: err}
+	}
+
+	distinct := make([]rank, len(ranks))
+	copy(distinct, ranks)
+	sort.Slice(distinct, func(i, j int) bool { return distinct[i].less(distinct[j]) })
+	priorities := map[rank]int{}
+	for _, r := range distinct {
+		if _, ok := priorities[r]; !ok {
+			priorities[r] = len(priorities)
+		}
+	}
+	for i := range records {
+		records[i].Priority = priorities[ranks[i]]
+	}
+	return sd.Event{Instances: instances, Records: records}
+}
+
+// rank is the priority of a record of a source, relative to all the records
+// of all the sources.
+type rank struct {
+	source, record int
+}
+
+func (r rank) less(other rank) bool {
+	if r.source != other.source {
+		return r.source < other.source
+	}
+	return r.record < other.record
+}
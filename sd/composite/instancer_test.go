@@ -0,0 +1,152 @@
+package composite
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	var (
+		zk     = instance.NewCache()
+		consul = instance.NewCache()
+	)
+	zk.Update(sd.Event{Instances: []string{"1.0.0.1:80", "1.0.0.2:80"}})
+	consul.Update(sd.Event{
+		Instances: []string{"1.0.0.2:80", "1.0.0.3:80"},
+		Records:   []sd.InstanceRecord{{Instance: "1.0.0.2:80", Zone: "a"}, {Instance: "1.0.0.3:80", Zone: "b"}},
+	})
+
+	in := NewInstancer([]Source{{zk, 1}, {consul, 0}}, log.NewNopLogger())
+	defer in.Stop()
+
+	// The duplicate instance comes from the preferred source.
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.2:80", "1.0.0.3:80")
+	want := []sd.InstanceRecord{
+		{Instance: "1.0.0.1:80", Priority: 1},
+		{Instance: "1.0.0.2:80", Zone: "a"},
+		{Instance: "1.0.0.3:80", Zone: "b"},
+	}
+	if have := in.cache.State().Records; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	consul.Update(sd.Event{Instances: []string{"1.0.0.4:80"}})
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.2:80", "1.0.0.4:80")
+}
+
+func TestInstancerRecordPriorities(t *testing.T) {
+	var (
+		srv    = instance.NewCache()
+		consul = instance.NewCache()
+	)
+	srv.Update(sd.Event{
+		Instances: []string{"1.0.0.1:80", "1.0.0.2:80"},
+		Records:   []sd.InstanceRecord{{Instance: "1.0.0.1:80", Priority: 20}, {Instance: "1.0.0.2:80", Priority: 10}},
+	})
+	consul.Update(sd.Event{
+		Instances: []string{"1.0.0.3:80"},
+		Records:   []sd.InstanceRecord{{Instance: "1.0.0.3:80", Priority: 10}},
+	})
+
+	in := NewInstancer([]Source{{srv, 0}, {consul, 1}}, log.NewNopLogger())
+	defer in.Stop()
+
+	// The records are preferred by source, then by their own priority.
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.2:80", "1.0.0.3:80")
+	want := []sd.InstanceRecord{
+		{Instance: "1.0.0.1:80", Priority: 1},
+		{Instance: "1.0.0.2:80", Priority: 0},
+		{Instance: "1.0.0.3:80", Priority: 2},
+	}
+	if have := in.cache.State().Records; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestInstancerLateSource(t *testing.T) {
+	var (
+		zk   = instance.NewCache()
+		late = &lateInstancer{}
+	)
+	zk.Update(sd.Event{Instances: []string{"1.0.0.1:80"}})
+
+	// Sources that don't send their state upon registration don't block.
+	in := NewInstancer([]Source{{Instancer: zk}, {Instancer: late}}, log.NewNopLogger())
+	defer in.Stop()
+	waitInstances(t, in, "1.0.0.1:80")
+
+	late.send(sd.Event{Instances: []string{"1.0.0.2:80"}})
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.2:80")
+}
+
+// lateInstancer only sends events when told to.
+type lateInstancer struct {
+	mtx sync.Mutex
+	ch  chan<- sd.Event
+}
+
+func (i *lateInstancer) Register(ch chan<- sd.Event) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	i.ch = ch
+}
+
+func (i *lateInstancer) Deregister(chan<- sd.Event) {}
+func (i *lateInstancer) Stop()                      {}
+
+func (i *lateInstancer) send(event sd.Event) {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+	i.ch <- event
+}
+
+func TestInstancerErrors(t *testing.T) {
+	var (
+		zk     = instance.NewCache()
+		consul = instance.NewCache()
+		zkErr  = errors.New("zk is down")
+	)
+	zk.Update(sd.Event{Instances: []string{"1.0.0.1:80"}})
+	consul.Update(sd.Event{Instances: []string{"1.0.0.2:80"}})
+
+	in := NewInstancer([]Source{{Instancer: zk}, {Instancer: consul}}, log.NewNopLogger())
+	defer in.Stop()
+
+	// Failing sources keep their last instances.
+	zk.Update(sd.Event{Err: zkErr})
+	consul.Update(sd.Event{Instances: []string{"1.0.0.3:80"}})
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.3:80")
+
+	// Errors are only passed through when all sources fail.
+	consul.Update(sd.Event{Err: errors.New("consul is down")})
+	for deadline := time.Now().Add(5 * time.Second); in.cache.State().Err != zkErr; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", zkErr, in.cache.State().Err)
+		}
+	}
+
+	zk.Update(sd.Event{Instances: []string{"1.0.0.4:80"}})
+	waitInstances(t, in, "1.0.0.3:80", "1.0.0.4:80")
+}
+
+func waitInstances(t *testing.T, in *Instancer, instances ...string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		state := in.cache.State()
+		if state.Err == nil && reflect.DeepEqual(instances, state.Instances) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", instances, state)
+		}
+	}
+}
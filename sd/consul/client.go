@@ -37,3 +37,19 @@ func (c *client) Deregister(r *consul.AgentServiceRegistration) error {
 func (c *client) Service(service, tag string, passingOnly bool, queryOpts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
 	return c.consul.Health().Service(service, tag, passingOnly, queryOpts)
 }
+
+func (c *client) ExecutePreparedQuery(query string, queryOpts *consul.QueryOptions) (*consul.PreparedQueryExecuteResponse, *consul.QueryMeta, error) {
+	return c.consul.PreparedQuery().Execute(query, queryOpts)
+}
+
+func (c *client) UpdateTTL(checkID, output, status string) error {
+	return c.consul.Agent().UpdateTTL(checkID, output, status)
+}
+
+func (c *client) EnableServiceMaintenance(serviceID, reason string) error {
+	return c.consul.Agent().EnableServiceMaintenance(serviceID, reason)
+}
+
+func (c *client) DisableServiceMaintenance(serviceID string) error {
+	return c.consul.Agent().DisableServiceMaintenance(serviceID)
+}
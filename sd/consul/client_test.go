@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"reflect"
+	"sync"
 	"testing"
 
 	stdconsul "github.com/hashicorp/consul/api"
@@ -58,6 +59,12 @@ func TestClientRegistration(t *testing.T) {
 
 type testClient struct {
 	entries []*stdconsul.ServiceEntry
+
+	mtx         sync.Mutex
+	ttls        map[string]string // check ID to status
+	maintenance map[string]bool   // service ID to maintenance mode
+	failing     map[string]error  // datacenter to error
+	queries     []stdconsul.QueryOptions
 }
 
 func newTestClient(entries []*stdconsul.ServiceEntry) *testClient {
@@ -69,12 +76,24 @@ func newTestClient(entries []*stdconsul.ServiceEntry) *testClient {
 var _ Client = &testClient{}
 
 func (c *testClient) Service(service, tag string, _ bool, opts *stdconsul.QueryOptions) ([]*stdconsul.ServiceEntry, *stdconsul.QueryMeta, error) {
+	c.mtx.Lock()
+	c.queries = append(c.queries, *opts)
+	err := c.failing[opts.Datacenter]
+	entries := c.entries
+	c.mtx.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var results []*stdconsul.ServiceEntry
 
-	for _, entry := range c.entries {
+	for _, entry := range entries {
 		if entry.Service.Service != service {
 			continue
 		}
+		if opts.Datacenter != "" && entry.Node.Datacenter != opts.Datacenter {
+			continue
+		}
 		if tag != "" {
 			tagMap := map[string]struct{}{}
 
@@ -93,6 +112,20 @@ func (c *testClient) Service(service, tag string, _ bool, opts *stdconsul.QueryO
 	return results, &stdconsul.QueryMeta{LastIndex: opts.WaitIndex}, nil
 }
 
+// ExecutePreparedQuery treats the query as a service name, and returns its
+// passing instances.
+func (c *testClient) ExecutePreparedQuery(query string, opts *stdconsul.QueryOptions) (*stdconsul.PreparedQueryExecuteResponse, *stdconsul.QueryMeta, error) {
+	entries, meta, err := c.Service(query, "", true, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	response := &stdconsul.PreparedQueryExecuteResponse{Service: query, Datacenter: opts.Datacenter}
+	for _, entry := range entries {
+		response.Nodes = append(response.Nodes, *entry)
+	}
+	return response, meta, nil
+}
+
 func (c *testClient) Register(r *stdconsul.AgentServiceRegistration) error {
 	toAdd := registration2entry(r)
 
@@ -124,6 +157,33 @@ func (c *testClient) Deregister(r *stdconsul.AgentServiceRegistration) error {
 	return nil
 }
 
+func (c *testClient) UpdateTTL(checkID, output, status string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.ttls == nil {
+		c.ttls = map[string]string{}
+	}
+	c.ttls[checkID] = status
+	return nil
+}
+
+func (c *testClient) EnableServiceMaintenance(serviceID, reason string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.maintenance == nil {
+		c.maintenance = map[string]bool{}
+	}
+	c.maintenance[serviceID] = true
+	return nil
+}
+
+func (c *testClient) DisableServiceMaintenance(serviceID string) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.maintenance, serviceID)
+	return nil
+}
+
 func registration2entry(r *stdconsul.AgentServiceRegistration) *stdconsul.ServiceEntry {
 	return &stdconsul.ServiceEntry{
 		Node: &stdconsul.Node{
@@ -3,6 +3,7 @@ package consul
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	consul "github.com/hashicorp/consul/api"
@@ -18,6 +19,18 @@ const defaultIndex = 0
 // errStopped notifies the loop to quit. aka stopped via quitc
 var errStopped = errors.New("quit and closed consul instancer")
 
+// errNoPreparedQueries is published by instancers using PreparedQuery with a
+// client that can't execute prepared queries.
+var errNoPreparedQueries = errors.New("consul: client doesn't support prepared queries")
+
+// preparedQuerier is implemented by clients that can execute prepared
+// queries, as the client returned by NewClient does. It's required by
+// PreparedQuery.
+type preparedQuerier interface {
+	// ExecutePreparedQuery executes a prepared query, by ID or name.
+	ExecutePreparedQuery(query string, queryOpts *consul.QueryOptions) (*consul.PreparedQueryExecuteResponse, *consul.QueryMeta, error)
+}
+
 // Instancer yields instances for a service in Consul.
 type Instancer struct {
 	cache       *instance.Cache
@@ -27,12 +40,82 @@ type Instancer struct {
 	tags        []string
 	passingOnly bool
 	quitc       chan struct{}
+
+	opts        consul.QueryOptions
+	datacenters []string
+	merge       bool
+	query       string
+	interval    time.Duration
+
+	mtx   sync.Mutex
+	state []datacenterState // indexed like datacenters
+}
+
+type datacenterState struct {
+	records []sd.InstanceRecord
+	err     error
+}
+
+// InstancerOption sets an optional parameter for instancers.
+type InstancerOption func(*Instancer)
+
+// Datacenter queries the given datacenter, rather than the one of the agent.
+func Datacenter(dc string) InstancerOption {
+	return func(s *Instancer) { s.datacenters = []string{dc} }
+}
+
+// Datacenters queries each of the given datacenters, in order of preference.
+// The instancer publishes the instances of the first datacenter that has any,
+// failing over to the next one when a datacenter has none or can't be
+// queried. Use MergeDatacenters to publish the instances of all of them.
+func Datacenters(dcs ...string) InstancerOption {
+	return func(s *Instancer) { s.datacenters = dcs }
+}
+
+// MergeDatacenters makes an instancer querying several datacenters publish
+// the instances of all of them, rather than failing over. The priority of
+// each instance record is the position of its datacenter in Datacenters, so
+// lower values are the preferred ones.
+func MergeDatacenters() InstancerOption {
+	return func(s *Instancer) { s.merge = true }
+}
+
+// Namespace queries the given Consul namespace, rather than the default one.
+func Namespace(namespace string) InstancerOption {
+	return func(s *Instancer) { s.opts.Namespace = namespace }
+}
+
+// Partition queries the given Consul admin partition, rather than the default
+// one.
+func Partition(partition string) InstancerOption {
+	return func(s *Instancer) { s.opts.Partition = partition }
+}
+
+// AllowStale allows any Consul server, rather than only the leader, to answer
+// queries. The results may be slightly out of date, but queries scale better
+// and keep working while the cluster has no leader.
+func AllowStale() InstancerOption {
+	return func(s *Instancer) { s.opts.AllowStale = true }
+}
+
+// PreparedQuery executes the given prepared query, by ID or name, rather than
+// querying the service by name. Prepared queries don't support blocking, so
+// they are executed every interval. The service name passed to NewInstancer
+// is then only used for logging, but the tags still filter the results.
+//
+// The client must implement ExecutePreparedQuery, as the client returned by
+// NewClient does; otherwise, the instancer only publishes an error.
+func PreparedQuery(query string, interval time.Duration) InstancerOption {
+	return func(s *Instancer) {
+		s.query = query
+		s.interval = interval
+	}
 }
 
 // NewInstancer returns a Consul instancer that publishes instances for the
 // requested service. It only returns instances for which all of the passed tags
 // are present.
-func NewInstancer(client Client, logger log.Logger, service string, tags []string, passingOnly bool) *Instancer {
+func NewInstancer(client Client, logger log.Logger, service string, tags []string, passingOnly bool, options ...InstancerOption) *Instancer {
 	s := &Instancer{
 		cache:       instance.NewCache(),
 		client:      client,
@@ -41,17 +124,46 @@ func NewInstancer(client Client, logger log.Logger, service string, tags []strin
 		tags:        tags,
 		passingOnly: passingOnly,
 		quitc:       make(chan struct{}),
+		datacenters: []string{""},
+		interval:    10 * time.Second,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if len(s.datacenters) == 0 {
+		s.datacenters = []string{""}
+	}
+	if s.interval <= 0 {
+		s.interval = 10 * time.Second
+	}
+	if _, ok := client.(preparedQuerier); s.query != "" && !ok {
+		s.logger.Log("err", errNoPreparedQueries)
+		s.cache.Update(sd.Event{Err: errNoPreparedQueries})
+		return s
+	}
+
+	s.state = make([]datacenterState, len(s.datacenters))
+	indexes := make([]uint64, len(s.datacenters))
+	for i, dc := range s.datacenters {
+		records, index, err := s.getInstances(dc, defaultIndex, nil)
+		if err != nil {
+			log.With(s.logger, "datacenter", dc).Log("err", err)
+		}
+		s.state[i] = datacenterState{records: records, err: err}
+		indexes[i] = index
 	}
 
-	instances, index, err := s.getInstances(defaultIndex, nil)
-	if err == nil {
-		s.logger.Log("instances", len(instances))
+	event := s.event()
+	if event.Err == nil {
+		s.logger.Log("instances", len(event.Instances))
 	} else {
-		s.logger.Log("err", err)
+		s.logger.Log("err", event.Err)
 	}
 
-	s.cache.Update(sd.Event{Instances: instances, Err: err})
-	go s.loop(index)
+	s.cache.Update(event)
+	for i, index := range indexes {
+		go s.loop(i, index)
+	}
 	return s
 }
 
@@ -60,41 +172,103 @@ func (s *Instancer) Stop() {
 	close(s.quitc)
 }
 
-func (s *Instancer) loop(lastIndex uint64) {
+func (s *Instancer) loop(i int, lastIndex uint64) {
 	var (
-		instances []string
-		err       error
-		d         time.Duration = 10 * time.Millisecond
-		index     uint64
+		dc      = s.datacenters[i]
+		logger  = s.logger
+		records []sd.InstanceRecord
+		err     error
+		d       time.Duration = 10 * time.Millisecond
+		index   uint64
 	)
+	if len(s.datacenters) > 1 {
+		logger = log.With(logger, "datacenter", dc)
+	}
 	for {
-		instances, index, err = s.getInstances(lastIndex, s.quitc)
+		if s.query != "" {
+			select {
+			case <-time.After(s.interval):
+			case <-s.quitc:
+				return
+			}
+		}
+
+		records, index, err = s.getInstances(dc, lastIndex, s.quitc)
 		switch {
 		case errors.Is(err, errStopped):
 			return // stopped via quitc
 		case err != nil:
-			s.logger.Log("err", err)
+			logger.Log("err", err)
 			time.Sleep(d)
 			d = conn.Exponential(d)
-			s.cache.Update(sd.Event{Err: err})
+			s.update(i, nil, err)
+		case s.query != "":
+			s.update(i, records, nil) // prepared queries don't block, so indexes don't matter
 		case index == defaultIndex:
-			s.logger.Log("err", "index is not sane")
+			logger.Log("err", "index is not sane")
 			time.Sleep(d)
 			d = conn.Exponential(d)
 		case index < lastIndex:
-			s.logger.Log("err", "index is less than previous; resetting to default")
+			logger.Log("err", "index is less than previous; resetting to default")
 			lastIndex = defaultIndex
 			time.Sleep(d)
 			d = conn.Exponential(d)
 		default:
 			lastIndex = index
-			s.cache.Update(sd.Event{Instances: instances})
+			s.update(i, records, nil)
 			d = 10 * time.Millisecond
 		}
 	}
 }
 
-func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]string, uint64, error) {
+// update records the result of querying the i-th datacenter, and publishes
+// the resulting instances.
+func (s *Instancer) update(i int, records []sd.InstanceRecord, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.state[i] = datacenterState{records: records, err: err}
+	s.cache.Update(s.event())
+}
+
+// event returns the instances to publish, given the state of each
+// datacenter. It only returns an error if all the datacenters failed. It
+// must be called with the mutex held, unless the loops aren't running yet.
+func (s *Instancer) event() sd.Event {
+	var (
+		records = []sd.InstanceRecord{}
+		seen    = map[string]bool{}
+		ok      bool
+		err     error
+	)
+	for i, state := range s.state {
+		if state.err != nil {
+			if err == nil {
+				err = state.err
+			}
+			continue
+		}
+		ok = true
+		for _, record := range state.records {
+			if seen[record.Instance] {
+				continue
+			}
+			seen[record.Instance] = true
+			if len(s.datacenters) > 1 {
+				record.Priority = i
+			}
+			records = append(records, record)
+		}
+		if len(records) > 0 && !s.merge {
+			break // fail over to the next datacenter only if this one has no instances
+		}
+	}
+	if !ok {
+		return sd.Event{Err: err}
+	}
+	return sd.Event{Instances: recordInstances(records), Records: records}
+}
+
+func (s *Instancer) getInstances(dc string, lastIndex uint64, interruptc chan struct{}) ([]sd.InstanceRecord, uint64, error) {
 	tag := ""
 	if len(s.tags) > 0 {
 		tag = s.tags[0]
@@ -107,29 +281,39 @@ func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]
 	// If we want blocking for efficiency, we must filter tags manually.
 
 	type response struct {
-		instances []string
-		index     uint64
+		records []sd.InstanceRecord
+		index   uint64
 	}
 
 	var (
 		errc = make(chan error, 1)
 		resc = make(chan response, 1)
+		opts = s.opts
 	)
+	opts.Datacenter = dc
+	opts.WaitIndex = lastIndex
 
 	go func() {
-		entries, meta, err := s.client.Service(s.service, tag, s.passingOnly, &consul.QueryOptions{
-			WaitIndex: lastIndex,
-		})
+		var (
+			entries []*consul.ServiceEntry
+			meta    *consul.QueryMeta
+			err     error
+		)
+		if s.query != "" {
+			entries, meta, err = s.preparedQuery(&opts)
+		} else {
+			entries, meta, err = s.client.Service(s.service, tag, s.passingOnly, &opts)
+			if len(s.tags) > 1 {
+				entries = filterEntries(entries, s.tags[1:]...)
+			}
+		}
 		if err != nil {
 			errc <- err
 			return
 		}
-		if len(s.tags) > 1 {
-			entries = filterEntries(entries, s.tags[1:]...)
-		}
 		resc <- response{
-			instances: makeInstances(entries),
-			index:     meta.LastIndex,
+			records: makeRecords(entries),
+			index:   meta.LastIndex,
 		}
 	}()
 
@@ -137,12 +321,27 @@ func (s *Instancer) getInstances(lastIndex uint64, interruptc chan struct{}) ([]
 	case err := <-errc:
 		return nil, 0, err
 	case res := <-resc:
-		return res.instances, res.index, nil
+		return res.records, res.index, nil
 	case <-interruptc:
 		return nil, 0, errStopped
 	}
 }
 
+// preparedQuery executes the prepared query. Prepared queries only return
+// passing instances, but may fail over to other datacenters themselves.
+func (s *Instancer) preparedQuery(opts *consul.QueryOptions) ([]*consul.ServiceEntry, *consul.QueryMeta, error) {
+	opts.WaitIndex = 0
+	response, meta, err := s.client.(preparedQuerier).ExecutePreparedQuery(s.query, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]*consul.ServiceEntry, len(response.Nodes))
+	for i := range response.Nodes {
+		entries[i] = &response.Nodes[i]
+	}
+	return filterEntries(entries, s.tags...), meta, nil
+}
+
 // Register implements Instancer.
 func (s *Instancer) Register(ch chan<- sd.Event) {
 	s.cache.Register(ch)
@@ -185,3 +384,27 @@ func makeInstances(entries []*consul.ServiceEntry) []string {
 	}
 	return instances
 }
+
+// makeRecords returns the records corresponding to makeInstances, carrying
+// each service's weight, tags and meta.
+func makeRecords(entries []*consul.ServiceEntry) []sd.InstanceRecord {
+	instances := makeInstances(entries)
+	records := make([]sd.InstanceRecord, len(entries))
+	for i, entry := range entries {
+		records[i] = sd.InstanceRecord{
+			Instance: instances[i],
+			Weight:   entry.Service.Weights.Passing,
+			Tags:     entry.Service.Tags,
+			Meta:     entry.Service.Meta,
+		}
+	}
+	return records
+}
+
+func recordInstances(records []sd.InstanceRecord) []string {
+	instances := make([]string, len(records))
+	for i, record := range records {
+		instances[i] = record.Instance
+	}
+	return instances
+}
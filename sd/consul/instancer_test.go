@@ -2,8 +2,10 @@ package consul
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
 	"testing"
 	"time"
 
@@ -78,6 +80,42 @@ func TestInstancer(t *testing.T) {
 	}
 }
 
+func TestInstancerRecords(t *testing.T) {
+	var (
+		logger = log.NewNopLogger()
+		client = newTestClient([]*consul.ServiceEntry{
+			{
+				Node: &consul.Node{Address: "10.0.0.0", Node: "app00.local"},
+				Service: &consul.AgentService{
+					ID:      "search-api-0",
+					Port:    8000,
+					Service: "search",
+					Tags:    []string{"api"},
+					Meta:    map[string]string{"version": "1.2.3"},
+					Weights: consul.AgentWeights{Passing: 10, Warning: 1},
+				},
+			},
+		})
+	)
+
+	s := NewInstancer(client, logger, "search", []string{"api"}, true)
+	defer s.Stop()
+
+	records := s.cache.State().Records
+	if want, have := 1, len(records); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "10.0.0.0:8000", records[0].Instance; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 10, records[0].Weight; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "1.2.3", records[0].Meta["version"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
 func TestInstancerNoService(t *testing.T) {
 	var (
 		logger = log.NewNopLogger()
@@ -135,6 +173,109 @@ func TestInstancerAddressOverride(t *testing.T) {
 	}
 }
 
+func TestInstancerQueryOptions(t *testing.T) {
+	client := newTestClient(consulState)
+	s := NewInstancer(client, log.NewNopLogger(), "search", []string{"api"}, true,
+		Datacenter("dc2"), Namespace("team"), Partition("part"), AllowStale())
+	defer s.Stop()
+
+	client.mtx.Lock()
+	opts := client.queries[0]
+	client.mtx.Unlock()
+	if want, have := (consul.QueryOptions{Datacenter: "dc2", Namespace: "team", Partition: "part", AllowStale: true}), opts; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestInstancerDatacenters(t *testing.T) {
+	var (
+		myErr   = errors.New("unreachable")
+		entries = []*consul.ServiceEntry{
+			dcEntry("dc1", "10.0.1.0"),
+			dcEntry("dc2", "10.0.2.0"),
+			dcEntry("dc2", "10.0.2.1"),
+			dcEntry("dc3", "10.0.3.0"),
+		}
+		client = newTestClient(entries)
+	)
+	client.failing = map[string]error{"dc1": myErr}
+
+	// The first datacenter fails, so the instancer fails over to the next one.
+	failover := NewInstancer(client, log.NewNopLogger(), "search", nil, true, Datacenters("dc1", "dc2", "dc3"))
+	defer failover.Stop()
+	if want, have := []string{"10.0.2.0:80", "10.0.2.1:80"}, failover.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	merged := NewInstancer(client, log.NewNopLogger(), "search", nil, true, Datacenters("dc1", "dc3", "dc2"), MergeDatacenters())
+	defer merged.Stop()
+	state := merged.cache.State()
+	if want, have := []string{"10.0.2.0:80", "10.0.2.1:80", "10.0.3.0:80"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	for _, record := range state.Records {
+		want := 2
+		if record.Instance == "10.0.3.0:80" {
+			want = 1
+		}
+		if have := record.Priority; want != have {
+			t.Errorf("%s: want priority %d, have %d", record.Instance, want, have)
+		}
+	}
+
+	client = newTestClient(entries)
+	client.failing = map[string]error{"dc1": myErr, "dc2": myErr, "dc3": myErr}
+	failed := NewInstancer(client, log.NewNopLogger(), "search", nil, true, Datacenters("dc1", "dc2", "dc3"))
+	defer failed.Stop()
+	if want, have := myErr, failed.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestInstancerPreparedQuery(t *testing.T) {
+	events := make(chan sd.Event, 1)
+	client := newTestClient(nil)
+	s := NewInstancer(client, log.NewNopLogger(), "search", []string{"api"}, true, PreparedQuery("search", time.Millisecond))
+	defer s.Stop()
+	s.Register(events)
+	defer s.Deregister(events)
+
+	if want, have := 0, len((<-events).Instances); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+
+	client.mtx.Lock()
+	client.entries = consulState
+	client.mtx.Unlock()
+	for {
+		select {
+		case event := <-events:
+			if want, have := []string{"10.0.0.0:8000", "10.0.0.1:8001"}, event.Instances; reflect.DeepEqual(want, have) {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("prepared query wasn't executed again")
+		}
+	}
+}
+
+func TestInstancerPreparedQueryUnsupported(t *testing.T) {
+	client := struct{ Client }{newTestClient(consulState)}
+	s := NewInstancer(client, log.NewNopLogger(), "search", nil, true, PreparedQuery("search", time.Millisecond))
+	defer s.Stop()
+
+	if want, have := errNoPreparedQueries, s.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func dcEntry(dc, address string) *consul.ServiceEntry {
+	return &consul.ServiceEntry{
+		Node:    &consul.Node{Address: address, Datacenter: dc},
+		Service: &consul.AgentService{Service: "search", Port: 80},
+	}
+}
+
 type eofTestClient struct {
 	client *testClient
 	eofSig chan bool
@@ -1,18 +1,51 @@
 package consul
 
 import (
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
 	"github.com/go-kit/log"
 )
 
+// errNoAgentChecks is returned by MarkUnavailable if the client can't put
+// services into maintenance mode.
+var errNoAgentChecks = errors.New("consul: client doesn't support maintenance mode")
+
+// agentChecks is implemented by clients that can update the checks of the
+// services of the local agent, as the client returned by NewClient does. The
+// Registrar keeps TTL checks passing, and supports MarkUnavailable, only with
+// such clients.
+type agentChecks interface {
+	// UpdateTTL sets the status of a TTL check with the local agent, e.g.
+	// consul.HealthPassing.
+	UpdateTTL(checkID, output, status string) error
+
+	// EnableServiceMaintenance puts a service of the local agent into
+	// maintenance mode, which makes it fail its health checks.
+	EnableServiceMaintenance(serviceID, reason string) error
+
+	// DisableServiceMaintenance takes a service of the local agent out of
+	// maintenance mode.
+	DisableServiceMaintenance(serviceID string) error
+}
+
 // Registrar registers service instance liveness information to Consul.
+//
+// If the registration includes TTL checks, and the client supports it, the
+// Registrar keeps them passing in the background, updating them twice per
+// TTL, until it deregisters.
 type Registrar struct {
 	client       Client
 	registration *stdconsul.AgentServiceRegistration
 	logger       log.Logger
+
+	mtx         sync.Mutex
+	quitc       chan struct{} // non-nil while heartbeating
+	maintenance bool
 }
 
 // NewRegistrar returns a Consul Registrar acting on the provided catalog
@@ -25,20 +58,141 @@ func NewRegistrar(client Client, r *stdconsul.AgentServiceRegistration, logger l
 	}
 }
 
-// Register implements sd.Registrar interface.
+// Register implements sd.Registrar interface. It also takes the service out
+// of maintenance mode, if MarkUnavailable put it there.
 func (p *Registrar) Register() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
 	if err := p.client.Register(p.registration); err != nil {
 		p.logger.Log("err", err)
-	} else {
-		p.logger.Log("action", "register")
+		return
+	}
+	p.logger.Log("action", "register")
+
+	a, ok := p.client.(agentChecks)
+	if !ok {
+		return
+	}
+
+	if p.maintenance {
+		if err := a.DisableServiceMaintenance(p.serviceID()); err != nil {
+			p.logger.Log("during", "DisableServiceMaintenance", "err", err)
+		} else {
+			p.maintenance = false
+		}
+	}
+
+	if checks, interval := p.ttlChecks(); len(checks) > 0 && p.quitc == nil {
+		p.quitc = make(chan struct{})
+		p.heartbeat(a, checks)
+		go p.loop(a, checks, interval, p.quitc)
 	}
 }
 
 // Deregister implements sd.Registrar interface.
 func (p *Registrar) Deregister() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.stop()
 	if err := p.client.Deregister(p.registration); err != nil {
 		p.logger.Log("err", err)
 	} else {
 		p.logger.Log("action", "deregister")
 	}
 }
+
+// MarkUnavailable implements sd.Drainer. It puts the service into maintenance
+// mode, so its health checks fail and other instances stop sending traffic,
+// and stops updating its TTL checks. It fails if the client doesn't support
+// maintenance mode.
+func (p *Registrar) MarkUnavailable() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	a, ok := p.client.(agentChecks)
+	if !ok {
+		return errNoAgentChecks
+	}
+
+	p.stop()
+	if err := a.EnableServiceMaintenance(p.serviceID(), "draining"); err != nil {
+		p.logger.Log("during", "EnableServiceMaintenance", "err", err)
+		return err
+	}
+	p.maintenance = true
+	p.logger.Log("action", "maintenance")
+	return nil
+}
+
+// stop must be called with the mutex held.
+func (p *Registrar) stop() {
+	if p.quitc != nil {
+		close(p.quitc)
+		p.quitc = nil
+	}
+}
+
+func (p *Registrar) loop(a agentChecks, checks []string, interval time.Duration, quitc chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.heartbeat(a, checks)
+		case <-quitc:
+			return
+		}
+	}
+}
+
+func (p *Registrar) heartbeat(a agentChecks, checks []string) {
+	for _, checkID := range checks {
+		if err := a.UpdateTTL(checkID, "", stdconsul.HealthPassing); err != nil {
+			p.logger.Log("during", "heartbeat", "check", checkID, "err", err)
+		}
+	}
+}
+
+func (p *Registrar) serviceID() string {
+	if p.registration.ID != "" {
+		return p.registration.ID
+	}
+	return p.registration.Name
+}
+
+// ttlChecks returns the IDs of the TTL checks of the registration, named as
+// Consul does if they don't have an explicit ID, and the interval at which to
+// update them.
+func (p *Registrar) ttlChecks() ([]string, time.Duration) {
+	var all []*stdconsul.AgentServiceCheck
+	if p.registration.Check != nil {
+		all = append(all, p.registration.Check)
+	}
+	all = append(all, p.registration.Checks...)
+
+	var (
+		checks   []string
+		interval time.Duration
+	)
+	for i, check := range all {
+		ttl, err := time.ParseDuration(check.TTL)
+		if err != nil || ttl <= 0 {
+			continue
+		}
+		id := check.CheckID
+		if id == "" {
+			id = "service:" + p.serviceID()
+			if len(all) > 1 {
+				id += fmt.Sprintf(":%d", i+1)
+			}
+		}
+		checks = append(checks, id)
+		if interval == 0 || ttl/2 < interval {
+			interval = ttl / 2
+		}
+	}
+	return checks, interval
+}
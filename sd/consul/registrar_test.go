@@ -1,7 +1,9 @@
 package consul
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	stdconsul "github.com/hashicorp/consul/api"
 
@@ -25,3 +27,84 @@ func TestRegistrar(t *testing.T) {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
+
+func TestRegistrarHeartbeat(t *testing.T) {
+	var (
+		client       = newTestClient([]*stdconsul.ServiceEntry{})
+		registration = &stdconsul.AgentServiceRegistration{
+			ID:   "my-id",
+			Name: "my-name",
+			Checks: stdconsul.AgentServiceChecks{
+				{TTL: "20ms"},
+				{HTTP: "http://localhost/health", Interval: "10s"},
+				{CheckID: "explicit", TTL: "1m"},
+			},
+		}
+		p = NewRegistrar(client, registration, log.NewNopLogger())
+	)
+
+	p.Register()
+	client.mtx.Lock()
+	if want, have := map[string]string{"service:my-id:1": "passing", "explicit": "passing"}, client.ttls; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	client.ttls = nil
+	client.mtx.Unlock()
+
+	// The checks are kept passing.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		client.mtx.Lock()
+		updated := len(client.ttls) > 0
+		client.mtx.Unlock()
+		if updated {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("TTL checks weren't updated")
+		}
+	}
+
+	if err := p.MarkUnavailable(); err != nil {
+		t.Fatal(err)
+	}
+	client.mtx.Lock()
+	if want, have := true, client.maintenance["my-id"]; want != have {
+		t.Errorf("want maintenance %v, have %v", want, have)
+	}
+	client.mtx.Unlock()
+
+	p.Deregister()
+	if want, have := 0, len(client.entries); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestRegistrarWithoutAgentChecks(t *testing.T) {
+	var (
+		client       = newTestClient([]*stdconsul.ServiceEntry{})
+		registration = &stdconsul.AgentServiceRegistration{
+			ID:    "my-id",
+			Name:  "my-name",
+			Check: &stdconsul.AgentServiceCheck{TTL: "20ms"},
+		}
+		p = NewRegistrar(struct{ Client }{client}, registration, log.NewNopLogger())
+	)
+
+	// Clients without the optional methods still register, but don't keep
+	// TTL checks passing.
+	p.Register()
+	if want, have := 1, len(client.entries); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := 0, len(client.ttls); want != have {
+		t.Errorf("want %d TTL updates, have %d", want, have)
+	}
+
+	if err := p.MarkUnavailable(); err == nil {
+		t.Error("want error, have none")
+	}
+	p.Deregister()
+	if want, have := 0, len(client.entries); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -17,7 +17,8 @@ import (
 var ErrPortZero = errors.New("resolver returned SRV record with port 0")
 
 // Instancer yields instances from the named DNS SRV record. The name is
-// resolved on a fixed schedule. Priorities and weights are ignored.
+// resolved on a fixed schedule. Priorities and weights are published as
+// instance records.
 type Instancer struct {
 	cache  *instance.Cache
 	name   string
@@ -50,13 +51,13 @@ func NewInstancerDetailed(
 		quit:   make(chan struct{}),
 	}
 
-	instances, err := p.resolve(lookup)
+	instances, records, err := p.resolve(lookup)
 	if err == nil {
 		logger.Log("name", name, "instances", len(instances))
 	} else {
 		logger.Log("name", name, "err", err)
 	}
-	p.cache.Update(sd.Event{Instances: instances, Err: err})
+	p.cache.Update(sd.Event{Instances: instances, Records: records, Err: err})
 
 	go p.loop(refresh, lookup)
 	return p
@@ -72,13 +73,13 @@ func (in *Instancer) loop(t *time.Ticker, lookup Lookup) {
 	for {
 		select {
 		case <-t.C:
-			instances, err := in.resolve(lookup)
+			instances, records, err := in.resolve(lookup)
 			if err != nil {
 				in.logger.Log("name", in.name, "err", err)
 				in.cache.Update(sd.Event{Err: err})
 				continue // don't replace potentially-good with bad
 			}
-			in.cache.Update(sd.Event{Instances: instances})
+			in.cache.Update(sd.Event{Instances: instances, Records: records})
 
 		case <-in.quit:
 			return
@@ -86,19 +87,25 @@ func (in *Instancer) loop(t *time.Ticker, lookup Lookup) {
 	}
 }
 
-func (in *Instancer) resolve(lookup Lookup) ([]string, error) {
+func (in *Instancer) resolve(lookup Lookup) ([]string, []sd.InstanceRecord, error) {
 	_, addrs, err := lookup("", "", in.name)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	instances := make([]string, len(addrs))
+	records := make([]sd.InstanceRecord, len(addrs))
 	for i, addr := range addrs {
 		if addr.Port == 0 {
-			return nil, ErrPortZero
+			return nil, nil, ErrPortZero
 		}
 		instances[i] = net.JoinHostPort(addr.Target, fmt.Sprint(addr.Port))
+		records[i] = sd.InstanceRecord{
+			Instance: instances[i],
+			Weight:   int(addr.Weight),
+			Priority: int(addr.Priority),
+		}
 	}
-	return instances, nil
+	return instances, records, nil
 }
 
 // Register implements Instancer.
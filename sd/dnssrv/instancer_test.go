@@ -2,6 +2,7 @@ package dnssrv
 
 import (
 	"net"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -95,6 +96,29 @@ func TestIssue892(t *testing.T) {
 	}
 }
 
+func TestRecords(t *testing.T) {
+	ticker := time.NewTicker(time.Second)
+	ticker.Stop()
+
+	lookup := func(service, proto, name string) (string, []*net.SRV, error) {
+		return "cname", []*net.SRV{
+			{Target: "1.0.0.2", Port: 80, Priority: 20, Weight: 5},
+			{Target: "1.0.0.1", Port: 80, Priority: 10, Weight: 50},
+		}, nil
+	}
+
+	instancer := NewInstancerDetailed("name", ticker, lookup, log.NewNopLogger())
+	defer instancer.Stop()
+
+	want := []sd.InstanceRecord{
+		{Instance: "1.0.0.1:80", Priority: 10, Weight: 50},
+		{Instance: "1.0.0.2:80", Priority: 20, Weight: 5},
+	}
+	if have := instancer.cache.State().Records; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
 type nopCloser struct{}
 
 func (nopCloser) Close() error { return nil }
@@ -0,0 +1,116 @@
+package sd
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Drainer is implemented by Registrars that can take their instance out of
+// rotation without deregistering it, e.g. by putting it in maintenance mode.
+// Registering again makes the instance available.
+type Drainer interface {
+	MarkUnavailable() error
+}
+
+// Drain gracefully takes a service instance out of rotation. It marks the
+// instance unavailable if the Registrar is a Drainer, or deregisters it
+// otherwise; waits for the grace period, so clients notice; waits for the
+// requests in flight to complete; and finally deregisters the instance.
+//
+// If the context is done before all requests complete, the instance is
+// deregistered anyway, and the context's error is returned. The inflight
+// counter may be nil, in which case only the grace period is waited for.
+func Drain(ctx context.Context, r Registrar, inflight *InFlight, grace time.Duration) error {
+	deregistered := false
+	if d, ok := r.(Drainer); !ok || d.MarkUnavailable() != nil {
+		r.Deregister()
+		deregistered = true
+	}
+
+	err := wait(ctx, grace)
+	if err == nil && inflight != nil {
+		err = inflight.Wait(ctx)
+	}
+
+	if !deregistered {
+		r.Deregister()
+	}
+	return err
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// InFlight counts the requests a server is processing, so it can wait for
+// them to complete before going away. The zero value is ready to use.
+type InFlight struct {
+	mtx  sync.Mutex
+	n    int
+	idle chan struct{} // closed when n drops to zero, if anyone's waiting
+}
+
+// Add adds delta, which may be negative, to the number of requests in
+// flight.
+func (f *InFlight) Add(delta int) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.n += delta
+	if f.n <= 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// Len returns the number of requests in flight.
+func (f *InFlight) Len() int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.n
+}
+
+// Wait blocks until no requests are in flight, or the context is done.
+func (f *InFlight) Wait(ctx context.Context) error {
+	f.mtx.Lock()
+	if f.n <= 0 {
+		f.mtx.Unlock()
+		return nil
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mtx.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Middleware returns an endpoint middleware that counts the requests to the
+// wrapped endpoint as in flight.
+func (f *InFlight) Middleware() endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			f.Add(1)
+			defer f.Add(-1)
+			return next(ctx, request)
+		}
+	}
+}
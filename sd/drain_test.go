@@ -0,0 +1,102 @@
+package sd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	var (
+		inflight InFlight
+		release  = make(chan struct{})
+		started  = make(chan struct{})
+		e        = inflight.Middleware()(func(context.Context, interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return struct{}{}, nil
+		})
+	)
+	go e(context.Background(), struct{}{})
+	<-started
+	if want, have := 1, inflight.Len(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, inflight.Wait(ctx); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	close(release)
+	if err := inflight.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, inflight.Len(); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	var (
+		r        = &drainRecorder{}
+		inflight InFlight
+	)
+	inflight.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.record("done")
+		inflight.Add(-1)
+	}()
+	if err := Drain(context.Background(), r, &inflight, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "unavailable done deregister ", r.calls; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	var (
+		r        = &drainRecorder{failUnavailable: true}
+		inflight InFlight
+	)
+	inflight.Add(1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if want, have := context.DeadlineExceeded, Drain(ctx, r, &inflight, 0); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Registrars that can't be marked unavailable are deregistered right away.
+	if want, have := "unavailable deregister ", r.calls; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+type drainRecorder struct {
+	failUnavailable bool
+
+	mtx   sync.Mutex
+	calls string
+}
+
+func (r *drainRecorder) record(call string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.calls += call + " "
+}
+
+func (r *drainRecorder) Register()   { r.record("register") }
+func (r *drainRecorder) Deregister() { r.record("deregister") }
+
+func (r *drainRecorder) MarkUnavailable() error {
+	r.record("unavailable")
+	if r.failUnavailable {
+		return errors.New("not supported")
+	}
+	return nil
+}
@@ -20,9 +20,11 @@ type endpointCache struct {
 	cache              map[string]endpointCloser
 	err                error
 	endpoints          []endpoint.Endpoint
+	instanceEndpoints  []InstanceEndpoint
 	logger             log.Logger
 	invalidateDeadline time.Time
 	timeNow            func() time.Time
+	outliers           *outlierDetector // nil unless OutlierDetection is set
 }
 
 type endpointCloser struct {
@@ -32,13 +34,17 @@ type endpointCloser struct {
 
 // newEndpointCache returns a new, empty endpointCache.
 func newEndpointCache(factory Factory, logger log.Logger, options endpointerOptions) *endpointCache {
-	return &endpointCache{
+	c := &endpointCache{
 		options: options,
 		factory: factory,
 		cache:   map[string]endpointCloser{},
 		logger:  logger,
 		timeNow: time.Now,
 	}
+	if options.outlierDetection {
+		c.outliers = newOutlierDetector(options.outlierConfig, logger, func() time.Time { return c.timeNow() })
+	}
+	return c
 }
 
 // Update should be invoked by clients with a complete set of current instance
@@ -51,7 +57,7 @@ func (c *endpointCache) Update(event Event) {
 
 	// Happy path.
 	if event.Err == nil {
-		c.updateCache(event.Instances)
+		c.updateCache(event.Instances, event.Records)
 		c.err = nil
 		return
 	}
@@ -70,9 +76,15 @@ func (c *endpointCache) Update(event Event) {
 	return
 }
 
-func (c *endpointCache) updateCache(instances []string) {
+func (c *endpointCache) updateCache(instances []string, records []InstanceRecord) {
 	// Deterministic order (for later).
 	sort.Strings(instances)
+	instances = subset(instances, c.options.subsetClientID, c.options.subsetSize)
+
+	recordByInstance := make(map[string]InstanceRecord, len(records))
+	for _, r := range records {
+		recordByInstance[r.Instance] = r
+	}
 
 	// Produce the current set of services.
 	cache := make(map[string]endpointCloser, len(instances))
@@ -90,41 +102,74 @@ func (c *endpointCache) updateCache(instances []string) {
 			c.logger.Log("instance", instance, "err", err)
 			continue
 		}
+		if c.outliers != nil {
+			service = c.outliers.add(instance, service, closer)
+		}
 		cache[instance] = endpointCloser{service, closer}
 	}
 
 	// Close any leftover endpoints.
-	for _, sc := range c.cache {
+	for instance, sc := range c.cache {
+		if c.outliers != nil {
+			c.outliers.remove(instance)
+		}
 		if sc.Closer != nil {
 			sc.Closer.Close()
 		}
 	}
 
-	// Populate the slice of endpoints.
+	// Populate the slices of endpoints.
 	endpoints := make([]endpoint.Endpoint, 0, len(cache))
+	instanceEndpoints := make([]InstanceEndpoint, 0, len(cache))
 	for _, instance := range instances {
 		// A bad factory may mean an instance is not present.
 		if _, ok := cache[instance]; !ok {
 			continue
 		}
 		endpoints = append(endpoints, cache[instance].Endpoint)
+		record, ok := recordByInstance[instance]
+		if !ok {
+			record = InstanceRecord{Instance: instance}
+		}
+		instanceEndpoints = append(instanceEndpoints, InstanceEndpoint{InstanceRecord: record, Endpoint: cache[instance].Endpoint})
 	}
 
 	// Swap and trigger GC for old copies.
 	c.endpoints = endpoints
+	c.instanceEndpoints = instanceEndpoints
 	c.cache = cache
 }
 
 // Endpoints yields the current set of (presumably identical) endpoints, ordered
 // lexicographically by the corresponding instance string.
 func (c *endpointCache) Endpoints() ([]endpoint.Endpoint, error) {
+	endpoints, instanceEndpoints, err := c.get()
+	if err != nil || c.outliers == nil {
+		return endpoints, err
+	}
+	endpoints, _ = c.outliers.filter(instanceEndpoints)
+	return endpoints, nil
+}
+
+// InstanceEndpoints is like Endpoints, but yields each endpoint along with the
+// instance string it was created from.
+func (c *endpointCache) InstanceEndpoints() ([]InstanceEndpoint, error) {
+	_, instanceEndpoints, err := c.get()
+	if err != nil || c.outliers == nil {
+		return instanceEndpoints, err
+	}
+	_, instanceEndpoints = c.outliers.filter(instanceEndpoints)
+	return instanceEndpoints, nil
+}
+
+func (c *endpointCache) get() ([]endpoint.Endpoint, []InstanceEndpoint, error) {
 	// in the steady state we're going to have many goroutines calling Endpoints()
 	// concurrently, so to minimize contention we use a shared R-lock.
 	c.mtx.RLock()
 
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
 		defer c.mtx.RUnlock()
-		return c.endpoints, nil
+		return c.endpoints, c.instanceEndpoints, nil
 	}
 
 	c.mtx.RUnlock()
@@ -135,9 +180,9 @@ func (c *endpointCache) Endpoints() ([]endpoint.Endpoint, error) {
 
 	// re-check condition due to a race between RUnlock() and Lock().
 	if c.err == nil || c.timeNow().Before(c.invalidateDeadline) {
-		return c.endpoints, nil
+		return c.endpoints, c.instanceEndpoints, nil
 	}
 
-	c.updateCache(nil) // close any remaining active endpoints
-	return nil, c.err
+	c.updateCache(nil, nil) // close any remaining active endpoints
+	return nil, nil, c.err
 }
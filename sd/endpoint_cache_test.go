@@ -140,6 +140,30 @@ func TestEndpointCacheErrorAndTimeout(t *testing.T) {
 	assertEndpointsError(t, cache, "sd error") // expect original error
 }
 
+func TestEndpointCacheInstanceEndpoints(t *testing.T) {
+	var (
+		f     = func(instance string) (endpoint.Endpoint, io.Closer, error) { return endpoint.Nop, nil, nil }
+		cache = newEndpointCache(f, log.NewNopLogger(), endpointerOptions{})
+	)
+
+	cache.Update(Event{Instances: []string{"b", "a"}, Records: []InstanceRecord{{Instance: "b", Weight: 3}}})
+	instanceEndpoints, err := cache.InstanceEndpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(instanceEndpoints); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	for i, want := range []string{"a", "b"} {
+		if have := instanceEndpoints[i].Instance; want != have {
+			t.Errorf("%d: want %q, have %q", i, want, have)
+		}
+	}
+	if want, have := 3, instanceEndpoints[1].Weight; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
 func TestBadFactory(t *testing.T) {
 	cache := newEndpointCache(func(string) (endpoint.Endpoint, io.Closer, error) {
 		return nil, nil, errors.New("bad factory")
@@ -15,12 +15,43 @@ type Endpointer interface {
 	Endpoints() ([]endpoint.Endpoint, error)
 }
 
+// InstanceEndpoint is an endpoint along with the instance it was created
+// from. The record only carries the instance string, unless the Instancer
+// provided a full record in its Event.
+type InstanceEndpoint struct {
+	InstanceRecord
+	endpoint.Endpoint
+}
+
+// InstanceEndpointer is an Endpointer that can also yield its endpoints along
+// with their instance strings, for consumers that need to tell instances
+// apart, like load balancers that route requests by key.
+type InstanceEndpointer interface {
+	Endpointer
+	InstanceEndpoints() ([]InstanceEndpoint, error)
+}
+
 // FixedEndpointer yields a fixed set of endpoints.
 type FixedEndpointer []endpoint.Endpoint
 
 // Endpoints implements Endpointer.
 func (s FixedEndpointer) Endpoints() ([]endpoint.Endpoint, error) { return s, nil }
 
+// FixedInstanceEndpointer yields a fixed set of endpoints with instances.
+type FixedInstanceEndpointer []InstanceEndpoint
+
+// Endpoints implements Endpointer.
+func (s FixedInstanceEndpointer) Endpoints() ([]endpoint.Endpoint, error) {
+	endpoints := make([]endpoint.Endpoint, len(s))
+	for i, ie := range s {
+		endpoints[i] = ie.Endpoint
+	}
+	return endpoints, nil
+}
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (s FixedInstanceEndpointer) InstanceEndpoints() ([]InstanceEndpoint, error) { return s, nil }
+
 // NewEndpointer creates an Endpointer that subscribes to updates from Instancer src
 // and uses factory f to create Endpoints. If src notifies of an error, the Endpointer
 // keeps returning previously created Endpoints assuming they are still good, unless
@@ -60,6 +91,10 @@ func InvalidateOnError(timeout time.Duration) EndpointerOption {
 type endpointerOptions struct {
 	invalidateOnError bool
 	invalidateTimeout time.Duration
+	outlierDetection  bool
+	outlierConfig     OutlierConfig
+	subsetClientID    int
+	subsetSize        int
 }
 
 // DefaultEndpointer implements an Endpointer interface.
@@ -88,3 +123,8 @@ func (de *DefaultEndpointer) Close() {
 func (de *DefaultEndpointer) Endpoints() ([]endpoint.Endpoint, error) {
 	return de.cache.Endpoints()
 }
+
+// InstanceEndpoints implements InstanceEndpointer.
+func (de *DefaultEndpointer) InstanceEndpoints() ([]InstanceEndpoint, error) {
+	return de.cache.InstanceEndpoints()
+}
@@ -64,6 +64,8 @@ type client struct {
 	hbch <-chan *clientv3.LeaseKeepAliveResponse
 	// Lease interface instance, used to leverage Lease.Close()
 	leaser clientv3.Lease
+	// closed when the keepalive of the lease stops before the context is done
+	lost chan struct{}
 }
 
 // ClientOptions defines options for the etcd client. All values are optional.
@@ -132,6 +134,11 @@ func NewClient(ctx context.Context, machines []string, options ClientOptions) (C
 
 func (c *client) LeaseID() int64 { return int64(c.leaseID) }
 
+// leaseLost implements leaseWatcher. The keepalive of the lease stops when it
+// can't be renewed, e.g. because etcd was unreachable for longer than the
+// TTL, but also when the service is registered again or deregistered.
+func (c *client) leaseLost() <-chan struct{} { return c.lost }
+
 // GetEntries implements the etcd Client interface.
 func (c *client) GetEntries(key string) ([]string, error) {
 	resp, err := c.kv.Get(c.ctx, key, clientv3.WithPrefix())
@@ -214,12 +221,17 @@ func (c *client) Register(s Service) error {
 
 	// discard the keepalive response, make etcd library not to complain
 	// fix bug #799
+	hbch, lost := c.hbch, make(chan struct{})
+	c.lost = lost
 	go func() {
 		for {
 			select {
-			case r := <-c.hbch:
+			case r := <-hbch:
 				// avoid dead loop when channel was closed
 				if r == nil {
+					if c.ctx.Err() == nil {
+						close(lost)
+					}
 					return
 				}
 			case <-c.ctx.Done():
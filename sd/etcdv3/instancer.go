@@ -1,6 +1,9 @@
 package etcdv3
 
 import (
+	"encoding/json"
+	"strings"
+
 	"github.com/go-kit/kit/sd"
 	"github.com/go-kit/kit/sd/internal/instance"
 	"github.com/go-kit/log"
@@ -17,7 +20,10 @@ type Instancer struct {
 }
 
 // NewInstancer returns an etcd instancer. It will start watching the given
-// prefix for changes, and update the subscribers.
+// prefix for changes, and update the subscribers. Values that are JSON encoded
+// sd.InstanceRecords are published as instance records, and the instance
+// string they contain is used as the instance; other values are used as the
+// instance string directly.
 func NewInstancer(c Client, prefix string, logger log.Logger) (*Instancer, error) {
 	s := &Instancer{
 		client: c,
@@ -27,13 +33,14 @@ func NewInstancer(c Client, prefix string, logger log.Logger) (*Instancer, error
 		quitc:  make(chan struct{}),
 	}
 
-	instances, err := s.client.GetEntries(s.prefix)
+	entries, err := s.client.GetEntries(s.prefix)
 	if err == nil {
-		logger.Log("prefix", s.prefix, "instances", len(instances))
+		logger.Log("prefix", s.prefix, "instances", len(entries))
 	} else {
 		logger.Log("prefix", s.prefix, "err", err)
 	}
-	s.cache.Update(sd.Event{Instances: instances, Err: err})
+	instances, records := makeInstances(entries)
+	s.cache.Update(sd.Event{Instances: instances, Records: records, Err: err})
 
 	go s.loop()
 	return s, nil
@@ -46,13 +53,14 @@ func (s *Instancer) loop() {
 	for {
 		select {
 		case <-ch:
-			instances, err := s.client.GetEntries(s.prefix)
+			entries, err := s.client.GetEntries(s.prefix)
 			if err != nil {
 				s.logger.Log("msg", "failed to retrieve entries", "err", err)
 				s.cache.Update(sd.Event{Err: err})
 				continue
 			}
-			s.cache.Update(sd.Event{Instances: instances})
+			instances, records := makeInstances(entries)
+			s.cache.Update(sd.Event{Instances: instances, Records: records})
 
 		case <-s.quitc:
 			return
@@ -74,3 +82,22 @@ func (s *Instancer) Register(ch chan<- sd.Event) {
 func (s *Instancer) Deregister(ch chan<- sd.Event) {
 	s.cache.Deregister(ch)
 }
+
+// makeInstances converts the values stored in etcd to instance strings and
+// records. Values that don't decode as a record with an instance string are
+// taken as instance strings.
+func makeInstances(entries []string) ([]string, []sd.InstanceRecord) {
+	if entries == nil {
+		return nil, nil
+	}
+	instances := make([]string, len(entries))
+	records := make([]sd.InstanceRecord, len(entries))
+	for i, entry := range entries {
+		var record sd.InstanceRecord
+		if !strings.HasPrefix(strings.TrimSpace(entry), "{") || json.Unmarshal([]byte(entry), &record) != nil || record.Instance == "" {
+			record = sd.InstanceRecord{Instance: entry}
+		}
+		instances[i], records[i] = record.Instance, record
+	}
+	return instances, records
+}
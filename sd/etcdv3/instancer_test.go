@@ -2,6 +2,7 @@ package etcdv3
 
 import (
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/go-kit/kit/sd"
@@ -52,6 +53,35 @@ func TestInstancer(t *testing.T) {
 	}
 }
 
+func TestInstancerRecords(t *testing.T) {
+	client := &fakeClient{
+		responses: map[string]testResponse{"/foo": {
+			Kvs: []testKV{
+				{Key: []byte("/foo/1"), Value: []byte("1:1")},
+				{Key: []byte("/foo/2"), Value: []byte(`{"instance":"2:2","weight":3,"zone":"z1"}`)},
+			},
+		}},
+	}
+
+	s, err := NewInstancer(client, "/foo", log.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	state := s.cache.State()
+	if want, have := []string{"1:1", "2:2"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	want := []sd.InstanceRecord{
+		{Instance: "1:1"},
+		{Instance: "2:2", Weight: 3, Zone: "z1"},
+	}
+	if have := state.Records; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
 type fakeClient struct {
 	responses map[string]testResponse
 }
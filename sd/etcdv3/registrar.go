@@ -10,6 +10,10 @@ import (
 const minHeartBeatTime = 500 * time.Millisecond
 
 // Registrar registers service instance liveness information to etcd.
+//
+// The lease of the key is kept alive in the background. If it's lost anyway,
+// e.g. because etcd was unreachable for longer than the TTL, the Registrar
+// registers the service again, every heartbeat interval until it succeeds.
 type Registrar struct {
 	client  Client
 	service Service
@@ -24,7 +28,7 @@ type Registrar struct {
 // called the "instance" string in other parts of package sd.
 type Service struct {
 	Key   string // unique key, e.g. "/service/foobar/1.2.3.4:8080"
-	Value string // returned to subscribers, e.g. "http://1.2.3.4:8080"
+	Value string // returned to subscribers, e.g. "http://1.2.3.4:8080", or a JSON encoded sd.InstanceRecord
 	TTL   *TTLOption
 }
 
@@ -64,9 +68,18 @@ func NewRegistrar(client Client, service Service, logger log.Logger) *Registrar
 	}
 }
 
+// leaseWatcher is implemented by clients that report when the lease of the
+// registered service is lost.
+type leaseWatcher interface {
+	leaseLost() <-chan struct{}
+}
+
 // Register implements the sd.Registrar interface. Call it when you want your
 // service to be registered in etcd, typically at startup.
 func (r *Registrar) Register() {
+	r.quitmtx.Lock()
+	defer r.quitmtx.Unlock()
+
 	if err := r.client.Register(r.service); err != nil {
 		r.logger.Log("err", err)
 		return
@@ -76,21 +89,68 @@ func (r *Registrar) Register() {
 	} else {
 		r.logger.Log("action", "register")
 	}
+
+	if w, ok := r.client.(leaseWatcher); ok && r.quit == nil {
+		r.quit = make(chan struct{})
+		go r.loop(w, w.leaseLost(), r.quit)
+	}
 }
 
 // Deregister implements the sd.Registrar interface. Call it when you want your
 // service to be deregistered from etcd, typically just prior to shutdown.
 func (r *Registrar) Deregister() {
+	r.quitmtx.Lock()
+	defer r.quitmtx.Unlock()
+
 	if err := r.client.Deregister(r.service); err != nil {
 		r.logger.Log("err", err)
 	} else {
 		r.logger.Log("action", "deregister")
 	}
 
-	r.quitmtx.Lock()
-	defer r.quitmtx.Unlock()
 	if r.quit != nil {
 		close(r.quit)
 		r.quit = nil
 	}
 }
+
+func (r *Registrar) loop(w leaseWatcher, lost <-chan struct{}, quit chan struct{}) {
+	retry := minHeartBeatTime
+	if r.service.TTL != nil {
+		retry = r.service.TTL.heartbeat
+	}
+
+	for {
+		select {
+		case <-lost:
+		case <-quit:
+			return
+		}
+
+		r.quitmtx.Lock()
+		if r.quit != quit {
+			r.quitmtx.Unlock()
+			return // deregistered in the meantime
+		}
+		if current := w.leaseLost(); current != lost {
+			lost = current // registered again in the meantime
+			r.quitmtx.Unlock()
+			continue
+		}
+		err := r.client.Register(r.service)
+		if err == nil {
+			lost = w.leaseLost()
+			r.logger.Log("action", "reregister", "lease", r.client.LeaseID())
+		}
+		r.quitmtx.Unlock()
+
+		if err != nil {
+			r.logger.Log("during", "reregister", "err", err)
+			select {
+			case <-time.After(retry):
+			case <-quit:
+				return
+			}
+		}
+	}
+}
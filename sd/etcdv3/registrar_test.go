@@ -3,7 +3,9 @@ package etcdv3
 import (
 	"bytes"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-kit/log"
 )
@@ -116,3 +118,58 @@ func TestDeregister(t *testing.T) {
 		}
 	}
 }
+
+// leaseTestClient is a Client that reports lost leases.
+type leaseTestClient struct {
+	testClient
+
+	mtx       sync.Mutex
+	registers int
+	lost      chan struct{}
+}
+
+func (tc *leaseTestClient) Register(s Service) error {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	tc.registers++
+	tc.lost = make(chan struct{})
+	return nil
+}
+
+func (tc *leaseTestClient) leaseLost() <-chan struct{} {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	return tc.lost
+}
+
+func (tc *leaseTestClient) loseLease() {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	close(tc.lost)
+}
+
+func (tc *leaseTestClient) registrations() int {
+	tc.mtx.Lock()
+	defer tc.mtx.Unlock()
+	return tc.registers
+}
+
+func TestRegistrarReregistersOnLostLease(t *testing.T) {
+	c := &leaseTestClient{}
+	r := NewRegistrar(c, testService, log.NewNopLogger())
+
+	r.Register()
+	c.loseLease()
+	for deadline := time.Now().Add(5 * time.Second); c.registrations() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("service wasn't registered again")
+		}
+	}
+
+	r.Deregister()
+	c.loseLease() // as the client does when deregistering
+	time.Sleep(10 * time.Millisecond)
+	if want, have := 2, c.registrations(); want != have {
+		t.Errorf("want %d registrations, have %d", want, have)
+	}
+}
@@ -56,7 +56,7 @@ func (s *Instancer) consume(update fargo.AppUpdate) {
 	}
 	instances := convertFargoAppToInstances(update.App)
 	s.logger.Log("instances", len(instances))
-	s.cache.Update(sd.Event{Instances: instances})
+	s.cache.Update(sd.Event{Instances: instances, Records: convertFargoAppToRecords(update.App)})
 }
 
 func (s *Instancer) loop(updates <-chan fargo.AppUpdate, done chan<- struct{}) {
@@ -89,6 +89,34 @@ func convertFargoAppToInstances(app *fargo.Application) []string {
 	return instances
 }
 
+// convertFargoAppToRecords returns the records corresponding to
+// convertFargoAppToInstances. The zone is taken from the Amazon data center
+// info, the weight from the "weight" metadata key, if present, and all scalar
+// metadata values are passed on as strings.
+func convertFargoAppToRecords(app *fargo.Application) []sd.InstanceRecord {
+	instances := convertFargoAppToInstances(app)
+	records := make([]sd.InstanceRecord, len(app.Instances))
+	for i, inst := range app.Instances {
+		records[i] = sd.InstanceRecord{
+			Instance: instances[i],
+			Zone:     inst.DataCenterInfo.Metadata.AvailabilityZone,
+		}
+		if weight, err := inst.Metadata.GetInt("weight"); err == nil {
+			records[i].Weight = weight
+		}
+		for k, v := range inst.Metadata.GetMap() {
+			switch v.(type) {
+			case string, float64, bool:
+				if records[i].Meta == nil {
+					records[i].Meta = map[string]string{}
+				}
+				records[i].Meta[k] = fmt.Sprint(v)
+			}
+		}
+	}
+	return records
+}
+
 // Register implements Instancer.
 func (s *Instancer) Register(ch chan<- sd.Event) {
 	s.cache.Register(ch)
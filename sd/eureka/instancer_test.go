@@ -30,6 +30,36 @@ func TestInstancer(t *testing.T) {
 	}
 }
 
+func TestInstancerRecords(t *testing.T) {
+	instance := *instanceTest1
+	instance.DataCenterInfo = fargo.DataCenterInfo{
+		Name:     fargo.Amazon,
+		Metadata: fargo.AmazonMetadataType{AvailabilityZone: "us-east-1a"},
+	}
+	instance.Metadata.Raw = []byte(`{"weight": 7, "version": "1.2.3"}`)
+	connection := &testConnection{
+		instances:      []*fargo.Instance{&instance},
+		errApplication: nil,
+	}
+
+	instancer := NewInstancer(connection, appNameTest, loggerTest)
+	defer instancer.Stop()
+
+	records := instancer.state().Records
+	if want, have := 1, len(records); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := "us-east-1a", records[0].Zone; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := 7, records[0].Weight; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if want, have := "1.2.3", records[0].Meta["version"]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
 func TestInstancerReceivesUpdates(t *testing.T) {
 	connection := &testConnection{
 		instances:      []*fargo.Instance{instanceTest1},
@@ -21,6 +21,7 @@ type fargoConnection interface {
 	DeregisterInstance(instance *fargo.Instance) error
 	ReregisterInstance(instance *fargo.Instance) error
 	HeartBeatInstance(instance *fargo.Instance) error
+	UpdateInstanceStatus(instance *fargo.Instance, status fargo.StatusType) error
 	ScheduleAppUpdates(name string, await bool, done <-chan struct{}) <-chan fargo.AppUpdate
 	GetApp(name string) (*fargo.Application, error)
 }
@@ -41,9 +42,14 @@ type Registrar struct {
 	logger   log.Logger
 	quitc    chan chan struct{}
 	sync.Mutex
+
+	unavailable bool // set by MarkUnavailable
 }
 
-var _ sd.Registrar = (*Registrar)(nil)
+var (
+	_ sd.Registrar = (*Registrar)(nil)
+	_ sd.Drainer   = (*Registrar)(nil)
+)
 
 // NewRegistrar returns an Eureka Registrar acting on behalf of the provided
 // Fargo connection and instance. See the integration test for usage examples.
@@ -61,6 +67,9 @@ func (r *Registrar) Register() {
 	defer r.Unlock()
 
 	if r.quitc != nil {
+		if r.unavailable {
+			r.setStatus(fargo.UP)
+		}
 		return // Already in the registration loop.
 	}
 
@@ -85,6 +94,26 @@ func (r *Registrar) Deregister() {
 	r.quitc <- q
 	<-q
 	r.quitc = nil
+	r.unavailable = false
+}
+
+// MarkUnavailable implements sd.Drainer. It sets the status of the instance
+// to OUT_OF_SERVICE, and keeps sending heartbeats until Deregister is called.
+// Registering again sets the status back to UP.
+func (r *Registrar) MarkUnavailable() error {
+	r.Lock()
+	defer r.Unlock()
+	return r.setStatus(fargo.OUTOFSERVICE)
+}
+
+// setStatus must be called with the lock held.
+func (r *Registrar) setStatus(status fargo.StatusType) error {
+	if err := r.conn.UpdateInstanceStatus(r.instance, status); err != nil {
+		r.logger.Log("during", "UpdateInstanceStatus", "status", status, "err", err)
+		return err
+	}
+	r.unavailable = status != fargo.UP
+	return nil
 }
 
 func (r *Registrar) loop() {
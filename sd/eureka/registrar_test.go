@@ -3,6 +3,8 @@ package eureka
 import (
 	"testing"
 	"time"
+
+	"github.com/hudl/fargo"
 )
 
 func TestRegistrar(t *testing.T) {
@@ -100,3 +102,29 @@ func TestExpiredInstance(t *testing.T) {
 		t.Errorf("want %d, have %d", want, have)
 	}
 }
+
+func TestRegistrarMarkUnavailable(t *testing.T) {
+	connection := &testConnection{}
+	registrar := NewRegistrar(connection, instanceTest1, loggerTest)
+
+	registrar.Register()
+	if err := registrar.MarkUnavailable(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := fargo.OUTOFSERVICE, connection.statuses[instanceTest1.Id()]; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := 1, len(connection.instances); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	registrar.Register()
+	if want, have := fargo.UP, connection.statuses[instanceTest1.Id()]; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+
+	registrar.Deregister()
+	if want, have := 0, len(connection.instances); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
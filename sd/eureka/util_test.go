@@ -14,6 +14,7 @@ import (
 type testConnection struct {
 	mu        sync.RWMutex
 	instances []*fargo.Instance
+	statuses  map[string]fargo.StatusType
 
 	errApplication error
 	errHeartbeat   error
@@ -96,6 +97,16 @@ func (c *testConnection) DeregisterInstance(i *fargo.Instance) error {
 	return nil
 }
 
+func (c *testConnection) UpdateInstanceStatus(i *fargo.Instance, status fargo.StatusType) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.statuses == nil {
+		c.statuses = map[string]fargo.StatusType{}
+	}
+	c.statuses[i.Id()] = status
+	return nil
+}
+
 func (c *testConnection) ReregisterInstance(ins *fargo.Instance) error {
 	return nil
 }
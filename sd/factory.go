@@ -10,7 +10,8 @@ import (
 // specific endpoint. Instances that provide multiple endpoints require multiple
 // factories. A factory also returns an io.Closer that's invoked when the
 // instance goes away and needs to be cleaned up. Factories may return nil
-// closers.
+// closers. Closers that also implement HealthWatcher report the health of
+// their instance to the Endpointer.
 //
 // Users are expected to provide their own factory functions that assume
 // specific transports, or can deduce transports by parsing the instance string.
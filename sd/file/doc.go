@@ -0,0 +1,4 @@
+// Package file provides an Instancer implementation that reads instances from
+// a local file, for development and for environments without a service
+// discovery system.
+package file
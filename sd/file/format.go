@@ -0,0 +1,123 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kit/kit/sd"
+	"go.yaml.in/yaml/v2"
+)
+
+// ParseFunc parses the contents of an instance file. Records are optional; if
+// returned, there must be one per instance.
+type ParseFunc func(data []byte) (instances []string, records []sd.InstanceRecord, err error)
+
+// ErrEmptyInstance is returned by the parsers when an entry of the file
+// doesn't name an instance.
+var ErrEmptyInstance = errors.New("empty instance")
+
+// FormatFor returns the parser for the file, based on its extension: JSON for
+// .json, YAML for .yaml and .yml, and PlainText for anything else.
+func FormatFor(path string) ParseFunc {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return PlainText
+	}
+}
+
+// PlainText parses files with one instance per line. Leading and trailing
+// whitespace, blank lines, and lines starting with # are ignored.
+func PlainText(data []byte) ([]string, []sd.InstanceRecord, error) {
+	var (
+		instances []string
+		s         = bufio.NewScanner(bytes.NewReader(data))
+	)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		instances = append(instances, line)
+	}
+	return instances, nil, s.Err()
+}
+
+// JSON parses files with a JSON array. Every element is either an instance
+// string, or an object with the fields of sd.InstanceRecord, e.g.
+//
+//	["10.0.0.1:8080", {"instance": "10.0.0.2:8080", "weight": 2, "zone": "a"}]
+func JSON(data []byte) ([]string, []sd.InstanceRecord, error) {
+	var entries []entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, nil, err
+	}
+	return fromEntries(entries)
+}
+
+// YAML parses files with a YAML sequence. Every element is either an instance
+// string, or a mapping with the fields of sd.InstanceRecord, e.g.
+//
+//	# instances.yaml
+//	- 10.0.0.1:8080
+//	- instance: 10.0.0.2:8080
+//	  weight: 2
+//	  zone: a
+func YAML(data []byte) ([]string, []sd.InstanceRecord, error) {
+	var entries []entry
+	if err := yaml.UnmarshalStrict(data, &entries); err != nil {
+		return nil, nil, err
+	}
+	return fromEntries(entries)
+}
+
+// entry is an element of a JSON or YAML file, and remembers whether it was a
+// plain instance string.
+type entry struct {
+	sd.InstanceRecord
+	structured bool
+}
+
+func (e *entry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Instance); err == nil {
+		return nil
+	}
+	e.structured = true
+	return json.Unmarshal(data, &e.InstanceRecord)
+}
+
+func (e *entry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	if err := unmarshal(&e.Instance); err == nil {
+		return nil
+	}
+	e.structured = true
+	return unmarshal(&e.InstanceRecord)
+}
+
+// fromEntries returns records only if at least one entry was structured.
+func fromEntries(entries []entry) ([]string, []sd.InstanceRecord, error) {
+	var (
+		instances  = make([]string, len(entries))
+		records    = make([]sd.InstanceRecord, len(entries))
+		structured bool
+	)
+	for i, e := range entries {
+		if e.Instance == "" {
+			return nil, nil, fmt.Errorf("entry %d: %w", i, ErrEmptyInstance)
+		}
+		instances[i], records[i] = e.Instance, e.InstanceRecord
+		structured = structured || e.structured
+	}
+	if !structured {
+		records = nil
+	}
+	return instances, records, nil
+}
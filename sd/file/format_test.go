@@ -0,0 +1,76 @@
+package file
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/sd"
+)
+
+func TestFormats(t *testing.T) {
+	var (
+		instances = []string{"1.0.0.1:80", "1.0.0.2:80"}
+		records   = []sd.InstanceRecord{
+			{Instance: "1.0.0.1:80"},
+			{Instance: "1.0.0.2:80", Weight: 2, Zone: "a", Tags: []string{"canary"}, Meta: map[string]string{"v": "2"}},
+		}
+	)
+	for _, testcase := range []struct {
+		name    string
+		parse   ParseFunc
+		data    string
+		records []sd.InstanceRecord
+	}{
+		{"plain text", PlainText, "1.0.0.1:80\n  1.0.0.2:80  \n# 1.0.0.3:80\n", nil},
+		{"JSON strings", JSON, `["1.0.0.1:80", "1.0.0.2:80"]`, nil},
+		{"JSON records", JSON, `["1.0.0.1:80", {"instance": "1.0.0.2:80", "weight": 2, "zone": "a", "tags": ["canary"], "meta": {"v": "2"}}]`, records},
+		{"YAML strings", YAML, "- 1.0.0.1:80\n- 1.0.0.2:80\n", nil},
+		{"YAML records", YAML, "- 1.0.0.1:80\n- instance: 1.0.0.2:80\n  weight: 2\n  zone: a\n  tags: [canary]\n  meta: {v: \"2\"}\n", records},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			haveInstances, haveRecords, err := testcase.parse([]byte(testcase.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, have := instances, haveInstances; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+			if want, have := testcase.records, haveRecords; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	for name, err := range map[string]error{
+		"JSON syntax":     errorOf(JSON([]byte(`[`))),
+		"JSON empty":      errorOf(JSON([]byte(`[{"weight": 1}]`))),
+		"YAML unknown":    errorOf(YAML([]byte("- instanc: 1.0.0.1:80\n"))),
+		"YAML not a list": errorOf(YAML([]byte("instance: 1.0.0.1:80\n"))),
+	} {
+		if err == nil {
+			t.Errorf("%s: want error, have none", name)
+		}
+	}
+	if _, _, err := JSON([]byte(`[""]`)); !errors.Is(err, ErrEmptyInstance) {
+		t.Errorf("want %v, have %v", ErrEmptyInstance, err)
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	for path, want := range map[string]ParseFunc{
+		"a.json": JSON,
+		"a.YML":  YAML,
+		"a.yaml": YAML,
+		"a.txt":  PlainText,
+		"a":      PlainText,
+	} {
+		if have := FormatFor(path); reflect.ValueOf(have).Pointer() != reflect.ValueOf(want).Pointer() {
+			t.Errorf("%s: wrong format", path)
+		}
+	}
+}
+
+func errorOf(_ []string, _ []sd.InstanceRecord, err error) error { return err }
@@ -0,0 +1,107 @@
+package file
+
+import (
+	"os"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+// Instancer yields instances from a file. The file is checked for changes on
+// a fixed schedule, and re-read when its size or modification time changes.
+// Errors reading or parsing the file are published as events with an error.
+type Instancer struct {
+	cache  *instance.Cache
+	path   string
+	parse  ParseFunc
+	logger log.Logger
+	quit   chan struct{}
+
+	// owned by the loop goroutine
+	modTime time.Time
+	size    int64
+}
+
+// NewInstancer returns a file instancer. The format of the file is derived
+// from its extension, see FormatFor.
+func NewInstancer(path string, interval time.Duration, logger log.Logger) *Instancer {
+	return NewInstancerDetailed(path, time.NewTicker(interval), FormatFor(path), logger)
+}
+
+// NewInstancerDetailed is the same as NewInstancer, but allows users to
+// provide an explicit refresh ticker instead of an interval, and to specify
+// the format of the file.
+func NewInstancerDetailed(path string, refresh *time.Ticker, parse ParseFunc, logger log.Logger) *Instancer {
+	in := &Instancer{
+		cache:  instance.NewCache(),
+		path:   path,
+		parse:  parse,
+		logger: logger,
+		quit:   make(chan struct{}),
+	}
+
+	in.read()
+	go in.loop(refresh)
+	return in
+}
+
+// Stop terminates the Instancer.
+func (in *Instancer) Stop() {
+	close(in.quit)
+}
+
+func (in *Instancer) loop(t *time.Ticker) {
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			in.read()
+
+		case <-in.quit:
+			return
+		}
+	}
+}
+
+// read publishes the contents of the file, if it changed since the last
+// successful read.
+func (in *Instancer) read() {
+	fi, err := os.Stat(in.path)
+	if err == nil && fi.ModTime().Equal(in.modTime) && fi.Size() == in.size {
+		return // unchanged
+	}
+
+	var (
+		data      []byte
+		instances []string
+		records   []sd.InstanceRecord
+	)
+	if err == nil {
+		data, err = os.ReadFile(in.path)
+	}
+	if err == nil {
+		instances, records, err = in.parse(data)
+	}
+	if err != nil {
+		in.logger.Log("path", in.path, "err", err)
+		in.modTime, in.size = time.Time{}, 0 // retry on the next tick
+		in.cache.Update(sd.Event{Err: err})
+		return
+	}
+
+	in.logger.Log("path", in.path, "instances", len(instances))
+	in.modTime, in.size = fi.ModTime(), fi.Size()
+	in.cache.Update(sd.Event{Instances: instances, Records: records})
+}
+
+// Register implements Instancer.
+func (in *Instancer) Register(ch chan<- sd.Event) {
+	in.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (in *Instancer) Deregister(ch chan<- sd.Event) {
+	in.cache.Deregister(ch)
+}
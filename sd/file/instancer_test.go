@@ -0,0 +1,97 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.txt")
+	write(t, path, "# web\n1.0.0.1:80\n\n1.0.0.2:80\n", time.Now())
+
+	ticker := time.NewTicker(time.Hour)
+	ticker.Stop()
+	in := NewInstancerDetailed(path, ticker, PlainText, log.NewNopLogger())
+	defer in.Stop()
+
+	state := in.cache.State()
+	if want, have := []string{"1.0.0.1:80", "1.0.0.2:80"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// The file changes.
+	write(t, path, "1.0.0.3:80\n", time.Now().Add(time.Second))
+	in.read()
+	if want, have := []string{"1.0.0.3:80"}, in.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// The file goes away.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	in.read()
+	if in.cache.State().Err == nil {
+		t.Fatal("want error, have none")
+	}
+
+	// The file comes back.
+	write(t, path, "1.0.0.4:80\n", time.Now().Add(2*time.Second))
+	in.read()
+	if state := in.cache.State(); state.Err != nil || !reflect.DeepEqual([]string{"1.0.0.4:80"}, state.Instances) {
+		t.Fatalf("want [1.0.0.4:80], have %v (%v)", state.Instances, state.Err)
+	}
+}
+
+func TestInstancerParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.json")
+	write(t, path, `["1.0.0.1:80"`, time.Now())
+
+	in := NewInstancer(path, time.Hour, log.NewNopLogger())
+	defer in.Stop()
+
+	if in.cache.State().Err == nil {
+		t.Fatal("want error, have none")
+	}
+}
+
+func TestInstancerPolls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.yaml")
+	write(t, path, "- 1.0.0.1:80\n", time.Now())
+
+	in := NewInstancer(path, 10*time.Millisecond, log.NewNopLogger())
+	defer in.Stop()
+
+	events := make(chan sd.Event, 1)
+	in.Register(events)
+	defer in.Deregister(events)
+	<-events
+
+	write(t, path, "- 1.0.0.1:80\n- 1.0.0.2:80\n", time.Now().Add(time.Second))
+	select {
+	case event := <-events:
+		if want, have := []string{"1.0.0.1:80", "1.0.0.2:80"}, event.Instances; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update")
+	}
+}
+
+func write(t *testing.T, path, data string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,96 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Checker probes a single instance, as found in sd.Event.Instances. It
+// returns nil if the instance is healthy. Implementations must respect the
+// deadline of the context.
+type Checker interface {
+	Check(ctx context.Context, instance string) error
+}
+
+// CheckerFunc is an adapter to allow the use of ordinary functions as
+// Checkers.
+type CheckerFunc func(ctx context.Context, instance string) error
+
+// Check implements Checker.
+func (f CheckerFunc) Check(ctx context.Context, instance string) error {
+	return f(ctx, instance)
+}
+
+// TCPChecker returns a Checker that considers an instance healthy if a TCP
+// connection to it can be established. Instances must be host:port.
+func TCPChecker() Checker {
+	return CheckerFunc(func(ctx context.Context, instance string) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", instance)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	})
+}
+
+// HTTPChecker returns a Checker that issues a GET request for path to every
+// instance, and considers it healthy if it responds with a 2xx status code.
+// Instances may be host:port, in which case the http scheme is assumed, or
+// URLs with a scheme. If client is nil, http.DefaultClient is used.
+func HTTPChecker(client *http.Client, path string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context, instance string) error {
+		url := instance
+		if !strings.Contains(url, "://") {
+			url = "http://" + url
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(url, "/")+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body) // allow the connection to be reused
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s: unexpected status %s", instance, resp.Status)
+		}
+		return nil
+	})
+}
+
+// GRPCChecker returns a Checker that queries every instance with the standard
+// gRPC health checking protocol, and considers it healthy if it reports the
+// named service as SERVING. An empty service asks for the health of the
+// server as a whole. The options are passed to grpc.DialContext, and should
+// at least specify the transport credentials. A connection is established for
+// each check, and closed afterwards.
+func GRPCChecker(service string, options ...grpc.DialOption) Checker {
+	return CheckerFunc(func(ctx context.Context, instance string) error {
+		conn, err := grpc.DialContext(ctx, instance, options...)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if status := resp.GetStatus(); status != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("%s: service %q is %s", instance, service, status)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,60 @@
+package healthcheck
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestTCPChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := TCPChecker().Check(ctx, addr); err != nil {
+		t.Errorf("want healthy, have %v", err)
+	}
+	ln.Close()
+	if err := TCPChecker().Check(ctx, addr); err == nil {
+		t.Errorf("want unhealthy, have healthy")
+	}
+}
+
+func TestGRPCChecker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		server = grpc.NewServer()
+		hs     = health.NewServer()
+	)
+	healthpb.RegisterHealthServer(server, hs)
+	go server.Serve(ln)
+	defer server.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	checker := GRPCChecker("svc", grpc.WithTransportCredentials(insecure.NewCredentials()))
+
+	hs.SetServingStatus("svc", healthpb.HealthCheckResponse_SERVING)
+	if err := checker.Check(ctx, ln.Addr().String()); err != nil {
+		t.Errorf("want healthy, have %v", err)
+	}
+	hs.SetServingStatus("svc", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := checker.Check(ctx, ln.Addr().String()); err == nil {
+		t.Errorf("want unhealthy, have healthy")
+	}
+}
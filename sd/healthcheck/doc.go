@@ -0,0 +1,5 @@
+// Package healthcheck provides an Instancer decorator that actively probes
+// the instances yielded by another Instancer, and only publishes the healthy
+// ones. It's useful with sources that don't track the health of instances
+// themselves, like sd.FixedInstancer or sd/dnssrv.
+package healthcheck
@@ -0,0 +1,290 @@
+package healthcheck
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+// Instancer decorates another Instancer, and probes the instances it yields
+// with a Checker on a fixed schedule. Only healthy instances are published.
+//
+// Instances are considered unhealthy when they first appear, and are probed
+// immediately. They become healthy after HealthyThreshold consecutive
+// successful checks, and unhealthy again after UnhealthyThreshold consecutive
+// failed ones. Errors from the decorated Instancer are passed through.
+type Instancer struct {
+	cache   *instance.Cache
+	src     sd.Instancer
+	checker Checker
+	logger  log.Logger
+	options instancerOptions
+	events  chan sd.Event
+	results chan result
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// owned by the loop goroutine
+	targets map[string]*target
+	records map[string]sd.InstanceRecord
+	srcErr  error
+}
+
+// InstancerOption allows control of the health checking behavior.
+type InstancerOption func(*instancerOptions)
+
+type instancerOptions struct {
+	interval           time.Duration
+	timeout            time.Duration
+	jitter             float64
+	healthyThreshold   int
+	unhealthyThreshold int
+}
+
+// Interval sets how often every instance is probed. The default is 10
+// seconds.
+func Interval(d time.Duration) InstancerOption {
+	return func(o *instancerOptions) { o.interval = d }
+}
+
+// Timeout sets the deadline of every check. The default is 2 seconds.
+func Timeout(d time.Duration) InstancerOption {
+	return func(o *instancerOptions) { o.timeout = d }
+}
+
+// Jitter delays every scheduled check by a random duration, up to the given
+// fraction of the interval, to avoid probing all instances at the same time.
+// The fraction must be between 0 and 1. By default, there's no jitter.
+func Jitter(fraction float64) InstancerOption {
+	return func(o *instancerOptions) { o.jitter = fraction }
+}
+
+// HealthyThreshold sets the number of consecutive successful checks after
+// which an unhealthy instance is considered healthy. The default is 1.
+func HealthyThreshold(n int) InstancerOption {
+	return func(o *instancerOptions) { o.healthyThreshold = n }
+}
+
+// UnhealthyThreshold sets the number of consecutive failed checks after which
+// a healthy instance is considered unhealthy. The default is 2.
+func UnhealthyThreshold(n int) InstancerOption {
+	return func(o *instancerOptions) { o.unhealthyThreshold = n }
+}
+
+type target struct {
+	healthy   bool
+	successes int
+	failures  int
+	checking  bool
+}
+
+type result struct {
+	instance string
+	target   *target
+	err      error
+}
+
+// NewInstancer returns an Instancer that publishes the instances of src that
+// pass the checks of checker. Stopping the returned Instancer doesn't stop
+// src.
+func NewInstancer(src sd.Instancer, checker Checker, logger log.Logger, options ...InstancerOption) *Instancer {
+	opts := instancerOptions{
+		interval:           10 * time.Second,
+		timeout:            2 * time.Second,
+		healthyThreshold:   1,
+		unhealthyThreshold: 2,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+	if opts.healthyThreshold < 1 {
+		opts.healthyThreshold = 1
+	}
+	if opts.unhealthyThreshold < 1 {
+		opts.unhealthyThreshold = 1
+	}
+	if opts.jitter < 0 {
+		opts.jitter = 0
+	}
+	if opts.jitter > 1 {
+		opts.jitter = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := &Instancer{
+		cache:   instance.NewCache(),
+		src:     src,
+		checker: checker,
+		logger:  logger,
+		options: opts,
+		events:  make(chan sd.Event),
+		results: make(chan result),
+		ctx:     ctx,
+		cancel:  cancel,
+		targets: map[string]*target{},
+		records: map[string]sd.InstanceRecord{},
+	}
+
+	go in.loop()
+	src.Register(in.events)
+	return in
+}
+
+// Stop terminates the Instancer. It doesn't stop the decorated Instancer.
+func (in *Instancer) Stop() {
+	in.src.Deregister(in.events)
+	in.cancel()
+}
+
+// Register implements Instancer.
+func (in *Instancer) Register(ch chan<- sd.Event) {
+	in.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (in *Instancer) Deregister(ch chan<- sd.Event) {
+	in.cache.Deregister(ch)
+}
+
+func (in *Instancer) loop() {
+	ticker := time.NewTicker(in.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-in.events:
+			in.update(event)
+
+		case <-ticker.C:
+			for instance, t := range in.targets {
+				var delay time.Duration
+				if max := int64(in.options.jitter * float64(in.options.interval)); max > 0 {
+					delay = time.Duration(rand.Int63n(max))
+				}
+				in.check(instance, t, delay)
+			}
+
+		case r := <-in.results:
+			in.observe(r)
+
+		case <-in.ctx.Done():
+			return
+		}
+	}
+}
+
+func (in *Instancer) update(event sd.Event) {
+	if event.Err != nil {
+		// Keep probing the instances we know about, so they can be published
+		// right away when the source recovers.
+		in.srcErr = event.Err
+		in.publish()
+		return
+	}
+	in.srcErr = nil
+
+	current := make(map[string]bool, len(event.Instances))
+	for _, instance := range event.Instances {
+		current[instance] = true
+		if _, ok := in.targets[instance]; !ok {
+			t := &target{}
+			in.targets[instance] = t
+			in.check(instance, t, 0)
+		}
+	}
+	for instance := range in.targets {
+		if !current[instance] {
+			delete(in.targets, instance)
+		}
+	}
+
+	in.records = make(map[string]sd.InstanceRecord, len(event.Records))
+	for _, record := range event.Records {
+		in.records[record.Instance] = record
+	}
+
+	in.publish()
+}
+
+// check probes the instance after the given delay, unless it's still being
+// probed from a previous round.
+func (in *Instancer) check(instance string, t *target, delay time.Duration) {
+	if t.checking {
+		return
+	}
+	t.checking = true
+
+	go func() {
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-in.ctx.Done():
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(in.ctx, in.options.timeout)
+		err := in.checker.Check(ctx, instance)
+		cancel()
+
+		select {
+		case in.results <- result{instance: instance, target: t, err: err}:
+		case <-in.ctx.Done():
+		}
+	}()
+}
+
+func (in *Instancer) observe(r result) {
+	r.target.checking = false
+	if in.targets[r.instance] != r.target {
+		return // instance went away in the meantime
+	}
+
+	t := r.target
+	if r.err == nil {
+		t.successes, t.failures = t.successes+1, 0
+		if !t.healthy && t.successes >= in.options.healthyThreshold {
+			t.healthy = true
+			in.logger.Log("instance", r.instance, "healthy", true)
+			in.publish()
+		}
+		return
+	}
+
+	t.successes, t.failures = 0, t.failures+1
+	if t.healthy && t.failures >= in.options.unhealthyThreshold {
+		t.healthy = false
+		in.logger.Log("instance", r.instance, "healthy", false, "err", r.err)
+		in.publish()
+	}
+}
+
+func (in *Instancer) publish() {
+	if in.srcErr != nil {
+		in.cache.Update(sd.Event{Err: in.srcErr})
+		return
+	}
+
+	var (
+		instances []string
+		records   []sd.InstanceRecord
+	)
+	for instance, t := range in.targets {
+		if !t.healthy {
+			continue
+		}
+		instances = append(instances, instance)
+		if record, ok := in.records[instance]; ok {
+			records = append(records, record)
+		}
+	}
+	sort.Strings(instances)
+	in.cache.Update(sd.Event{Instances: instances, Records: records})
+}
@@ -0,0 +1,126 @@
+package healthcheck
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	var (
+		aHealthy, bHealthy int32 = 1, 0
+		a                        = healthServer(&aHealthy)
+		b                        = healthServer(&bHealthy)
+		aInstance                = strings.TrimPrefix(a.URL, "http://")
+		bInstance                = strings.TrimPrefix(b.URL, "http://")
+	)
+	defer a.Close()
+	defer b.Close()
+
+	instancer := NewInstancer(
+		sd.FixedInstancer{aInstance, bInstance},
+		HTTPChecker(nil, "/health"),
+		log.NewNopLogger(),
+		Interval(10*time.Millisecond),
+		Jitter(0.5),
+		UnhealthyThreshold(2),
+	)
+	defer instancer.Stop()
+
+	events := make(chan sd.Event, 1)
+	go instancer.Register(events)
+	defer instancer.Deregister(events)
+
+	waitFor(t, events, []string{aInstance})
+
+	atomic.StoreInt32(&bHealthy, 1)
+	waitFor(t, events, sorted(aInstance, bInstance))
+
+	atomic.StoreInt32(&aHealthy, 0)
+	waitFor(t, events, []string{bInstance})
+
+	// Closed servers are unhealthy, too.
+	b.Close()
+	waitFor(t, events, nil)
+}
+
+func TestInstancerPassesRecordsAndErrors(t *testing.T) {
+	var (
+		healthy int32 = 1
+		s             = healthServer(&healthy)
+		inst          = strings.TrimPrefix(s.URL, "http://")
+		record        = sd.InstanceRecord{Instance: inst, Weight: 3}
+		src           = instance.NewCache()
+	)
+	defer s.Close()
+
+	instancer := NewInstancer(src, HTTPChecker(nil, "/health"), log.NewNopLogger(), Interval(10*time.Millisecond))
+	defer instancer.Stop()
+
+	events := make(chan sd.Event, 1)
+	go instancer.Register(events)
+	defer instancer.Deregister(events)
+
+	src.Update(sd.Event{Instances: []string{inst}, Records: []sd.InstanceRecord{record}})
+	event := waitFor(t, events, []string{inst})
+	if want, have := []sd.InstanceRecord{record}, event.Records; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	srcErr := errors.New("source failed")
+	src.Update(sd.Event{Err: srcErr})
+	for event := range events {
+		if event.Err == srcErr {
+			break
+		}
+	}
+
+	src.Update(sd.Event{Instances: []string{inst}})
+	waitFor(t, events, []string{inst})
+}
+
+func healthServer(healthy *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if atomic.LoadInt32(healthy) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+}
+
+// waitFor reads events until one without error has the given instances.
+func waitFor(t *testing.T, events chan sd.Event, instances []string) sd.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Err == nil && reflect.DeepEqual(instances, event.Instances) {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %v", instances)
+		}
+	}
+}
+
+func sorted(instances ...string) []string {
+	if instances[0] > instances[1] {
+		instances[0], instances[1] = instances[1], instances[0]
+	}
+	return instances
+}
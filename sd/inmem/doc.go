@@ -0,0 +1,4 @@
+// Package inmem provides an in-memory service discovery system, with Registrar
+// and Instancer implementations. It's useful to exercise service discovery
+// flows in tests, and in programs that run all their services in one process.
+package inmem
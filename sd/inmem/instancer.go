@@ -0,0 +1,78 @@
+package inmem
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+// Instancer yields instances for a service in a Registry.
+type Instancer struct {
+	cache    *instance.Cache
+	registry *Registry
+	logger   log.Logger
+	service  string
+	tags     []string
+
+	mtx     sync.Mutex
+	version uint64 // of the last update
+}
+
+// NewInstancer returns an instancer that publishes instances for the
+// requested service. It only returns instances for which all of the passed
+// tags are present.
+func NewInstancer(registry *Registry, logger log.Logger, service string, tags []string) *Instancer {
+	in := &Instancer{
+		cache:    instance.NewCache(),
+		registry: registry,
+		logger:   log.With(logger, "service", service, "tags", fmt.Sprint(tags)),
+		service:  service,
+		tags:     tags,
+	}
+
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+
+	s := registry.service(service)
+	s.instancers[in] = struct{}{}
+	event := s.event(tags)
+	if event.Err == nil {
+		in.logger.Log("instances", len(event.Instances))
+	} else {
+		in.logger.Log("err", event.Err)
+	}
+	in.update(event, s.version) // no observers yet, so it can't block
+	return in
+}
+
+// update publishes the state of the service, unless a later one was already
+// published.
+func (in *Instancer) update(event sd.Event, version uint64) {
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	if version < in.version {
+		return
+	}
+	in.version = version
+	in.cache.Update(event)
+}
+
+// Stop terminates the instancer.
+func (in *Instancer) Stop() {
+	in.registry.mtx.Lock()
+	defer in.registry.mtx.Unlock()
+	delete(in.registry.service(in.service).instancers, in)
+}
+
+// Register implements Instancer.
+func (in *Instancer) Register(ch chan<- sd.Event) {
+	in.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (in *Instancer) Deregister(ch chan<- sd.Event) {
+	in.cache.Deregister(ch)
+}
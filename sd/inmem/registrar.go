@@ -0,0 +1,86 @@
+package inmem
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// Registrar registers service instance liveness information to a Registry.
+// If the registration has a TTL, the Registrar renews it in the background,
+// twice per TTL, until it deregisters.
+type Registrar struct {
+	registry     *Registry
+	registration Registration
+	logger       log.Logger
+
+	mtx   sync.Mutex
+	quitc chan struct{} // non-nil while renewing
+}
+
+// NewRegistrar returns a Registrar acting on the provided registration.
+func NewRegistrar(registry *Registry, r Registration, logger log.Logger) *Registrar {
+	return &Registrar{
+		registry:     registry,
+		registration: r,
+		logger:       log.With(logger, "service", r.Service, "tags", fmt.Sprint(r.Tags), "instance", r.Instance),
+	}
+}
+
+// Register implements sd.Registrar interface. It also makes the instance
+// available again, if MarkUnavailable was called.
+func (p *Registrar) Register() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err := p.registry.Register(p.registration); err != nil {
+		p.logger.Log("err", err)
+		return
+	}
+	p.registry.SetDown(p.registration.Service, p.registration.Instance, false)
+	p.logger.Log("action", "register")
+
+	if p.registration.TTL > 0 && p.quitc == nil {
+		p.quitc = make(chan struct{})
+		go p.loop(p.quitc)
+	}
+}
+
+// Deregister implements sd.Registrar interface.
+func (p *Registrar) Deregister() {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if p.quitc != nil {
+		close(p.quitc)
+		p.quitc = nil
+	}
+	p.registry.Deregister(p.registration.Service, p.registration.Instance)
+	p.logger.Log("action", "deregister")
+}
+
+// MarkUnavailable implements sd.Drainer. It hides the instance from
+// Instancers, while keeping it registered.
+func (p *Registrar) MarkUnavailable() error {
+	p.registry.SetDown(p.registration.Service, p.registration.Instance, true)
+	p.logger.Log("action", "unavailable")
+	return nil
+}
+
+func (p *Registrar) loop(quitc chan struct{}) {
+	ticker := time.NewTicker(p.registration.TTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.registry.Register(p.registration); err != nil {
+				p.logger.Log("during", "heartbeat", "err", err)
+			}
+		case <-quitc:
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package inmem
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// ErrInvalidRegistration is returned when registering without a service name
+// or an instance.
+var ErrInvalidRegistration = errors.New("registration requires a service and an instance")
+
+// Registration describes a service instance. Instancers publish the embedded
+// record along with the instance.
+type Registration struct {
+	Service string
+	sd.InstanceRecord
+
+	// TTL, if positive, is how long the registration lasts unless it's
+	// renewed by registering it again.
+	TTL time.Duration
+}
+
+// Registry is an in-memory service discovery system. It's safe for concurrent
+// use.
+type Registry struct {
+	now    func() time.Time
+	timers bool
+
+	mtx      sync.Mutex
+	services map[string]*service
+}
+
+type service struct {
+	entries    map[string]*entry
+	err        error
+	instancers map[*Instancer]struct{}
+	version    uint64 // incremented with every change
+}
+
+type entry struct {
+	Registration
+	expires time.Time // zero if the registration doesn't expire
+	down    bool
+	timer   *time.Timer
+}
+
+// Option sets an optional parameter for the Registry.
+type Option func(*Registry)
+
+// Clock makes the Registry use the given function to tell the time. Expired
+// registrations are then only removed when Expire is called, so tests can
+// control time entirely.
+func Clock(now func() time.Time) Option {
+	return func(r *Registry) {
+		r.now = now
+		r.timers = false
+	}
+}
+
+// NewRegistry returns an empty Registry. By default, registrations with a TTL
+// are removed as soon as they expire.
+func NewRegistry(options ...Option) *Registry {
+	r := &Registry{
+		now:      time.Now,
+		timers:   true,
+		services: map[string]*service{},
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Register adds the registration, or renews it if the instance is already
+// registered for the service. It fails if the service has an error set.
+func (r *Registry) Register(reg Registration) error {
+	if reg.Service == "" || reg.Instance == "" {
+		return ErrInvalidRegistration
+	}
+
+	r.mtx.Lock()
+	s := r.service(reg.Service)
+	if s.err != nil {
+		r.mtx.Unlock()
+		return s.err
+	}
+
+	e, ok := s.entries[reg.Instance]
+	if !ok {
+		e = &entry{}
+		s.entries[reg.Instance] = e
+	}
+	e.Registration = reg
+	e.expires = time.Time{}
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	if reg.TTL > 0 {
+		e.expires = r.now().Add(reg.TTL)
+		if r.timers {
+			e.timer = time.AfterFunc(reg.TTL, r.Expire)
+		}
+	}
+	updates := r.publish(s)
+	r.mtx.Unlock()
+
+	updates.apply()
+	return nil
+}
+
+// Deregister removes the instance from the service. It's a no-op if the
+// instance isn't registered.
+func (r *Registry) Deregister(serviceName, instance string) {
+	r.mtx.Lock()
+	s := r.service(serviceName)
+	e, ok := s.entries[instance]
+	if !ok {
+		r.mtx.Unlock()
+		return
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	delete(s.entries, instance)
+	updates := r.publish(s)
+	r.mtx.Unlock()
+
+	updates.apply()
+}
+
+// SetDown hides a registered instance from Instancers without deregistering
+// it, or shows it again, to simulate flapping.
+func (r *Registry) SetDown(serviceName, instance string, down bool) {
+	var updates updates
+	r.mtx.Lock()
+	s := r.service(serviceName)
+	if e, ok := s.entries[instance]; ok && e.down != down {
+		e.down = down
+		updates = r.publish(s)
+	}
+	r.mtx.Unlock()
+
+	updates.apply()
+}
+
+// SetError simulates a failure of the service discovery system for the
+// service: while err is set, Instancers publish it instead of instances, and
+// registrations fail with it. Setting a nil error restores normal operation.
+func (r *Registry) SetError(serviceName string, err error) {
+	r.mtx.Lock()
+	s := r.service(serviceName)
+	s.err = err
+	updates := r.publish(s)
+	r.mtx.Unlock()
+
+	updates.apply()
+}
+
+// Expire removes the registrations whose TTL elapsed.
+func (r *Registry) Expire() {
+	var updates updates
+	r.mtx.Lock()
+	now := r.now()
+	for _, s := range r.services {
+		var expired bool
+		for instance, e := range s.entries {
+			if !e.expires.IsZero() && !now.Before(e.expires) {
+				delete(s.entries, instance)
+				expired = true
+			}
+		}
+		if expired {
+			updates = append(updates, r.publish(s)...)
+		}
+	}
+	r.mtx.Unlock()
+
+	updates.apply()
+}
+
+// service must be called with the mutex held.
+func (r *Registry) service(name string) *service {
+	s, ok := r.services[name]
+	if !ok {
+		s = &service{
+			entries:    map[string]*entry{},
+			instancers: map[*Instancer]struct{}{},
+		}
+		r.services[name] = s
+	}
+	return s
+}
+
+// publish returns the updates sending the current state of the service to its
+// Instancers. It must be called with the mutex held, and the updates applied
+// once it's released, so that observers of the Instancers can neither stall
+// nor deadlock the Registry.
+func (r *Registry) publish(s *service) updates {
+	s.version++
+	updates := make(updates, 0, len(s.instancers))
+	for in := range s.instancers {
+		updates = append(updates, update{in, s.event(in.tags), s.version})
+	}
+	return updates
+}
+
+// update is the state of a service, to be sent to one of its Instancers.
+type update struct {
+	in      *Instancer
+	event   sd.Event
+	version uint64
+}
+
+type updates []update
+
+// apply sends the updates, skipping those overtaken by concurrent ones.
+func (us updates) apply() {
+	for _, u := range us {
+		u.in.update(u.event, u.version)
+	}
+}
+
+// event returns the instances of the service with all the given tags.
+func (s *service) event(tags []string) sd.Event {
+	if s.err != nil {
+		return sd.Event{Err: s.err}
+	}
+
+	var event sd.Event
+	names := make([]string, 0, len(s.entries))
+	for instance := range s.entries {
+		names = append(names, instance)
+	}
+	sort.Strings(names)
+
+ENTRIES:
+	for _, instance := range names {
+		e := s.entries[instance]
+		if e.down {
+			continue
+		}
+		for _, tag := range tags {
+			if !hasTag(e.Tags, tag) {
+				continue ENTRIES
+			}
+		}
+		event.Instances = append(event.Instances, instance)
+		event.Records = append(event.Records, e.InstanceRecord)
+	}
+	return event
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package inmem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+	"github.com/go-kit/log"
+)
+
+var (
+	_ sd.Instancer = (*Instancer)(nil) // API check
+	_ sd.Registrar = (*Registrar)(nil) // API check
+	_ sd.Drainer   = (*Registrar)(nil) // API check
+)
+
+func TestRegistry(t *testing.T) {
+	var (
+		registry = NewRegistry()
+		all      = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		tagged   = NewInstancer(registry, log.NewNopLogger(), "svc", []string{"a", "b"})
+	)
+	defer all.Stop()
+	defer tagged.Stop()
+
+	registry.Register(registration("svc", "1.0.0.1:80", 0, "a"))
+	registry.Register(registration("svc", "1.0.0.2:80", 0, "a", "b"))
+	registry.Register(registration("other", "1.0.0.3:80", 0))
+	assertInstances(t, all, "1.0.0.1:80", "1.0.0.2:80")
+	assertInstances(t, tagged, "1.0.0.2:80")
+
+	if want, have := []string{"a", "b"}, tagged.cache.State().Records[0].Tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	registry.SetDown("svc", "1.0.0.2:80", true)
+	assertInstances(t, all, "1.0.0.1:80")
+	assertInstances(t, tagged)
+
+	registry.SetDown("svc", "1.0.0.2:80", false)
+	assertInstances(t, tagged, "1.0.0.2:80")
+
+	registry.Deregister("svc", "1.0.0.2:80")
+	assertInstances(t, all, "1.0.0.1:80")
+}
+
+func TestRegistrySlowObserver(t *testing.T) {
+	var (
+		registry  = NewRegistry()
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		events    = make(chan sd.Event)
+	)
+	defer instancer.Stop()
+	go instancer.Register(events)
+	<-events
+
+	// Nobody reads the next event yet, so the update of the instancer blocks,
+	// but not the Registry.
+	go registry.Register(registration("svc", "1.0.0.1:80", 0))
+	time.Sleep(10 * time.Millisecond) // let it block
+	done := make(chan struct{})
+	go func() {
+		registry.Register(registration("other", "1.0.0.2:80", 0))
+		registry.Deregister("other", "1.0.0.2:80")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		go func() {
+			for range events { // unblock the registry, so the instancer stops
+			}
+		}()
+		t.Fatal("registry stalled by a slow observer")
+	}
+
+	if want, have := []string{"1.0.0.1:80"}, (<-events).Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestRegistryError(t *testing.T) {
+	var (
+		registry  = NewRegistry()
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		myErr     = errors.New("unavailable")
+	)
+	defer instancer.Stop()
+
+	registry.Register(registration("svc", "1.0.0.1:80", 0))
+	registry.SetError("svc", myErr)
+	if want, have := myErr, instancer.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := myErr, registry.Register(registration("svc", "1.0.0.2:80", 0)); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	registry.SetError("svc", nil)
+	assertInstances(t, instancer, "1.0.0.1:80")
+}
+
+func TestRegistryTTL(t *testing.T) {
+	var (
+		now       = time.Now()
+		registry  = NewRegistry(Clock(func() time.Time { return now }))
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+	)
+	defer instancer.Stop()
+
+	registry.Register(registration("svc", "1.0.0.1:80", time.Minute))
+	registry.Register(registration("svc", "1.0.0.2:80", 0))
+
+	now = now.Add(30 * time.Second)
+	registry.Register(registration("svc", "1.0.0.1:80", time.Minute)) // renew
+	now = now.Add(59 * time.Second)
+	registry.Expire()
+	assertInstances(t, instancer, "1.0.0.1:80", "1.0.0.2:80")
+
+	now = now.Add(time.Second)
+	registry.Expire()
+	assertInstances(t, instancer, "1.0.0.2:80")
+}
+
+func TestRegistryTTLTimer(t *testing.T) {
+	var (
+		registry  = NewRegistry()
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		events    = make(chan sd.Event, 1)
+	)
+	defer instancer.Stop()
+
+	registry.Register(registration("svc", "1.0.0.1:80", 10*time.Millisecond))
+	instancer.Register(events)
+	defer instancer.Deregister(events)
+
+	for {
+		select {
+		case event := <-events:
+			if len(event.Instances) == 0 {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("registration didn't expire")
+		}
+	}
+}
+
+func TestRegistrarWithEndpointer(t *testing.T) {
+	var (
+		registry  = NewRegistry()
+		registrar = NewRegistrar(registry, registration("svc", "1.0.0.1:80", 0), log.NewNopLogger())
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		factory   = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) { return instance, nil }, nil, nil
+		}
+		endpointer = sd.NewEndpointer(instancer, factory, log.NewNopLogger())
+		balancer   = lb.NewRoundRobin(endpointer)
+	)
+	defer instancer.Stop()
+	defer endpointer.Close()
+
+	registrar.Register()
+	var e endpoint.Endpoint
+	for deadline := time.Now().Add(5 * time.Second); e == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("endpoint never showed up")
+		}
+		e, _ = balancer.Endpoint()
+		time.Sleep(time.Millisecond)
+	}
+	response, _ := e(context.Background(), struct{}{})
+	if want, have := "1.0.0.1:80", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	registrar.Deregister()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if _, err := balancer.Endpoint(); err == lb.ErrNoEndpoints {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("endpoint never went away")
+		}
+	}
+}
+
+func TestRegistrarHeartbeatAndDrain(t *testing.T) {
+	var (
+		registry  = NewRegistry()
+		registrar = NewRegistrar(registry, registration("svc", "1.0.0.1:80", 20*time.Millisecond), log.NewNopLogger())
+		instancer = NewInstancer(registry, log.NewNopLogger(), "svc", nil)
+		inflight  = &sd.InFlight{}
+	)
+	defer instancer.Stop()
+
+	// The registration outlives its TTL.
+	registrar.Register()
+	time.Sleep(50 * time.Millisecond)
+	assertInstances(t, instancer, "1.0.0.1:80")
+
+	inflight.Add(1)
+	done := make(chan error)
+	go func() { done <- sd.Drain(context.Background(), registrar, inflight, 0) }()
+
+	// The instance is hidden, but stays registered while requests are in
+	// flight.
+	for deadline := time.Now().Add(5 * time.Second); len(instancer.cache.State().Instances) > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("instance wasn't marked unavailable")
+		}
+	}
+	registry.mtx.Lock()
+	registered := len(registry.services["svc"].entries)
+	registry.mtx.Unlock()
+	if want, have := 1, registered; want != have {
+		t.Errorf("want %d registrations, have %d", want, have)
+	}
+
+	inflight.Add(-1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	registry.mtx.Lock()
+	registered = len(registry.services["svc"].entries)
+	registry.mtx.Unlock()
+	if want, have := 0, registered; want != have {
+		t.Errorf("want %d registrations, have %d", want, have)
+	}
+}
+
+func registration(service, instance string, ttl time.Duration, tags ...string) Registration {
+	return Registration{
+		Service:        service,
+		InstanceRecord: sd.InstanceRecord{Instance: instance, Tags: tags},
+		TTL:            ttl,
+	}
+}
+
+func assertInstances(t *testing.T, instancer *Instancer, instances ...string) {
+	t.Helper()
+	state := instancer.cache.State()
+	if state.Err != nil {
+		t.Fatalf("unexpected error %v", state.Err)
+	}
+	if len(instances) == 0 {
+		instances = nil
+	}
+	if want, have := instances, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
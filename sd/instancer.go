@@ -9,11 +9,28 @@ package sd
 // resource instances as stale (although it may choose to continue using them).
 // If the Instancer is able to restore connection to the discovery backend it must push
 // another Event with the current set of resource instances.
+//
+// Instancers backed by discovery systems that know more about instances than
+// their address, like weights or tags, may additionally populate Records.
+// Consumers that only need instance strings can ignore it.
 type Event struct {
 	Instances []string
+	Records   []InstanceRecord // optional, one per element of Instances
 	Err       error
 }
 
+// InstanceRecord carries structured information about a resource instance, as
+// reported by the service discovery system. Fields that the system doesn't
+// provide are left as zero values.
+type InstanceRecord struct {
+	Instance string            `json:"instance"`           // as found in Event.Instances, e.g. host:port
+	Weight   int               `json:"weight,omitempty"`   // relative weight; zero means unspecified
+	Priority int               `json:"priority,omitempty"` // lower values are preferred, as with DNS SRV
+	Zone     string            `json:"zone,omitempty"`     // locality, e.g. availability zone
+	Tags     []string          `json:"tags,omitempty"`
+	Meta     map[string]string `json:"meta,omitempty"`
+}
+
 // Instancer listens to a service discovery system and notifies registered
 // observers of changes in the resource instances. Every event sent to the channels
 // contains a complete set of instances known to the Instancer. That complete set is
@@ -30,6 +30,7 @@ func (c *Cache) Update(event sd.Event) {
 	defer c.mtx.Unlock()
 
 	sort.Strings(event.Instances)
+	sort.Slice(event.Records, func(i, j int) bool { return event.Records[i].Instance < event.Records[j].Instance })
 	if reflect.DeepEqual(c.state, event) {
 		return // no need to broadcast the same instances
 	}
@@ -92,11 +93,27 @@ func copyEvent(e sd.Event) sd.Event {
 	// observers all need their own copy of event
 	// because they can directly modify event.Instances
 	// for example, by calling sort.Strings
-	if e.Instances == nil {
-		return e
+	if e.Instances != nil {
+		instances := make([]string, len(e.Instances))
+		copy(instances, e.Instances)
+		e.Instances = instances
+	}
+	if e.Records != nil {
+		records := make([]sd.InstanceRecord, len(e.Records))
+		for i, r := range e.Records {
+			if r.Tags != nil {
+				r.Tags = append([]string(nil), r.Tags...)
+			}
+			if r.Meta != nil {
+				meta := make(map[string]string, len(r.Meta))
+				for k, v := range r.Meta {
+					meta[k] = v
+				}
+				r.Meta = meta
+			}
+			records[i] = r
+		}
+		e.Records = records
 	}
-	instances := make([]string, len(e.Instances))
-	copy(instances, e.Instances)
-	e.Instances = instances
 	return e
 }
@@ -46,6 +46,25 @@ func TestCache(t *testing.T) {
 	close(r1)
 }
 
+func TestCacheRecords(t *testing.T) {
+	cache := NewCache()
+	cache.Update(sd.Event{
+		Instances: []string{"y", "x"},
+		Records:   []sd.InstanceRecord{{Instance: "y", Weight: 2}, {Instance: "x", Tags: []string{"t"}}},
+	})
+
+	state := cache.State()
+	if want, have := []string{"x", "y"}, []string{state.Records[0].Instance, state.Records[1].Instance}; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	// Observers get their own copy of the records.
+	state.Records[0].Tags[0] = "modified"
+	if want, have := "t", cache.State().Records[0].Tags[0]; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
 func expectUpdate(t *testing.T, r chan sd.Event, expect []string) {
 	select {
 	case e := <-r:
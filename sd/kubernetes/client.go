@@ -0,0 +1,231 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ServiceNameLabel is the label linking EndpointSlices to their Service.
+const ServiceNameLabel = "kubernetes.io/service-name"
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Client is a wrapper around the Kubernetes API.
+type Client interface {
+	// EndpointSlices lists the EndpointSlices of the service, and returns the
+	// resource version of the list.
+	EndpointSlices(ctx context.Context, namespace, service string) ([]EndpointSlice, string, error)
+
+	// WatchEndpointSlices watches the EndpointSlices of the service for
+	// changes after the given resource version, and calls f with every change,
+	// until the server closes the watch, which returns nil, or the context is
+	// done.
+	WatchEndpointSlices(ctx context.Context, namespace, service, resourceVersion string, f func(WatchEvent)) error
+
+	// SetPodCondition sets the status of a condition of the pod, e.g. one
+	// listed in its readiness gates.
+	SetPodCondition(ctx context.Context, namespace, pod, conditionType string, status bool) error
+}
+
+// WatchEvent is a change of an EndpointSlice, delivered by a watch.
+type WatchEvent struct {
+	// Type is ADDED, MODIFIED or DELETED.
+	Type string
+
+	// Slice is the EndpointSlice after the change, or its last state if it
+	// was deleted. Its resource version is the one to resume the watch from.
+	Slice EndpointSlice
+}
+
+type client struct {
+	apiServer string
+	http      *http.Client
+	token     func() (string, error)
+}
+
+// NewClient returns an implementation of the Client interface, talking to the
+// API server at the given URL, e.g. https://kubernetes.default.svc. The HTTP
+// client must trust the certificate of the API server. If token isn't empty,
+// it's sent as a bearer token.
+func NewClient(apiServer string, httpClient *http.Client, token string) Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{
+		apiServer: strings.TrimSuffix(apiServer, "/"),
+		http:      httpClient,
+		token:     func() (string, error) { return token, nil },
+	}
+}
+
+// NewInClusterClient returns an implementation of the Client interface for
+// programs running in a pod, using the pod's service account. The token is
+// re-read for every request, since Kubernetes rotates it.
+func NewInClusterClient() (Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("not running in a Kubernetes cluster")
+	}
+	ca, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("no certificates in service account CA bundle")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &client{
+		apiServer: "https://" + net.JoinHostPort(host, port),
+		http:      &http.Client{Transport: transport},
+		token: func() (string, error) {
+			token, err := os.ReadFile(serviceAccountDir + "/token")
+			return strings.TrimSpace(string(token)), err
+		},
+	}, nil
+}
+
+func (c *client) EndpointSlices(ctx context.Context, namespace, service string) ([]EndpointSlice, string, error) {
+	resp, err := c.do(ctx, "GET", endpointSlicesPath(namespace, service, nil), "", nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	var list struct {
+		Metadata ObjectMeta      `json:"metadata"`
+		Items    []EndpointSlice `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, "", err
+	}
+	return list.Items, list.Metadata.ResourceVersion, nil
+}
+
+func (c *client) WatchEndpointSlices(ctx context.Context, namespace, service, resourceVersion string, f func(WatchEvent)) error {
+	path := endpointSlicesPath(namespace, service, url.Values{
+		"watch":           {"true"},
+		"resourceVersion": {resourceVersion},
+	})
+	resp, err := c.do(ctx, "GET", path, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var event struct {
+			Type   string          `json:"type"`
+			Object json.RawMessage `json:"object"`
+		}
+		if err := dec.Decode(&event); err == io.EOF {
+			return nil // the server closed the watch
+		} else if err != nil {
+			return err
+		}
+		switch event.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			var slice EndpointSlice
+			if err := json.Unmarshal(event.Object, &slice); err != nil {
+				return err
+			}
+			f(WatchEvent{Type: event.Type, Slice: slice})
+		case "ERROR":
+			return fmt.Errorf("watch: %s", statusMessage(event.Object))
+		}
+	}
+}
+
+func (c *client) SetPodCondition(ctx context.Context, namespace, pod, conditionType string, status bool) error {
+	type condition struct {
+		Type               string `json:"type"`
+		Status             string `json:"status"`
+		LastTransitionTime string `json:"lastTransitionTime"`
+	}
+	var patch struct {
+		Status struct {
+			Conditions []condition `json:"conditions"`
+		} `json:"status"`
+	}
+	patch.Status.Conditions = []condition{{
+		Type:               conditionType,
+		Status:             map[bool]string{true: "True", false: "False"}[status],
+		LastTransitionTime: time.Now().UTC().Format(time.RFC3339),
+	}}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/status", url.PathEscape(namespace), url.PathEscape(pod))
+	resp, err := c.do(ctx, "PATCH", path, "application/strategic-merge-patch+json", body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// do issues the request, and returns an error for unsuccessful responses.
+func (c *client) do(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.apiServer+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	token, err := c.token()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		if message := statusMessage(data); message != "" {
+			return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, message)
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	return resp, nil
+}
+
+func endpointSlicesPath(namespace, service string, query url.Values) string {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("labelSelector", ServiceNameLabel+"="+service)
+	return fmt.Sprintf("/apis/discovery.k8s.io/v1/namespaces/%s/endpointslices?%s", url.PathEscape(namespace), query.Encode())
+}
+
+// statusMessage returns the message of a Kubernetes Status object.
+func statusMessage(data []byte) string {
+	var status struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(data, &status)
+	return status.Message
+}
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClientEndpointSlices(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "/apis/discovery.k8s.io/v1/namespaces/default/endpointslices", r.URL.Path; want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if want, have := ServiceNameLabel+"=web", r.URL.Query().Get("labelSelector"); want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if want, have := "Bearer secret", r.Header.Get("Authorization"); want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if r.URL.Query().Get("watch") == "true" {
+			fmt.Fprintln(w, `{"type": "BOOKMARK", "object": {}}`)
+			fmt.Fprintln(w, `{"type": "MODIFIED", "object": {"metadata": {"name": "web-1", "resourceVersion": "43"}}}`)
+			fmt.Fprintln(w, `{"type": "DELETED", "object": {"metadata": {"name": "web-1", "resourceVersion": "44"}}}`)
+			return
+		}
+		fmt.Fprint(w, `{
+			"metadata": {"resourceVersion": "42"},
+			"items": [{
+				"metadata": {"name": "web-1"},
+				"addressType": "IPv4",
+				"endpoints": [{"addresses": ["10.0.0.1"], "conditions": {"ready": true}, "zone": "a"}],
+				"ports": [{"name": "http", "port": 8080, "protocol": "TCP"}]
+			}]
+		}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, server.Client(), "secret")
+	slices, resourceVersion, err := client.EndpointSlices(context.Background(), "default", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "42", resourceVersion; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	instances, _ := makeInstances(slices, "http")
+	if want, have := []string{"10.0.0.1:8080"}, instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	var events []string
+	err = client.WatchEndpointSlices(context.Background(), "default", "web", resourceVersion, func(event WatchEvent) {
+		events = append(events, event.Type+" "+event.Slice.Metadata.Name+" "+event.Slice.Metadata.ResourceVersion)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if want, have := []string{"MODIFIED web-1 43", "DELETED web-1 44"}, events; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("watch") == "true" {
+			fmt.Fprintln(w, `{"type": "ERROR", "object": {"kind": "Status", "message": "too old resource version", "code": 410}}`)
+			return
+		}
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"kind": "Status", "message": "endpointslices is forbidden", "code": 403}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil, "")
+	if _, _, err := client.EndpointSlices(context.Background(), "default", "web"); err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("want forbidden error, have %v", err)
+	}
+	if err := client.WatchEndpointSlices(context.Background(), "default", "web", "1", func(WatchEvent) {}); err == nil || !strings.Contains(err.Error(), "too old") {
+		t.Errorf("want watch error, have %v", err)
+	}
+}
+
+func TestClientSetPodCondition(t *testing.T) {
+	var patch struct {
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want, have := "PATCH /api/v1/namespaces/default/pods/web-0/status", r.Method+" "+r.URL.Path; want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		if want, have := "application/strategic-merge-patch+json", r.Header.Get("Content-Type"); want != have {
+			t.Errorf("want %s, have %s", want, have)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &patch); err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, nil, "")
+	if err := client.SetPodCondition(context.Background(), "default", "web-0", "example.com/registered", false); err != nil {
+		t.Fatal(err)
+	}
+	if len(patch.Status.Conditions) != 1 {
+		t.Fatalf("want 1 condition, have %d", len(patch.Status.Conditions))
+	}
+	if want, have := "example.com/registered", patch.Status.Conditions[0].Type; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	if want, have := "False", patch.Status.Conditions[0].Status; want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+}
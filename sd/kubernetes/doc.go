@@ -0,0 +1,4 @@
+// Package kubernetes provides Instancer and Registrar implementations for
+// Kubernetes. The Instancer watches the EndpointSlices of a Service; the
+// Registrar sets a custom pod condition, for use with pod readiness gates.
+package kubernetes
@@ -0,0 +1,231 @@
+package kubernetes
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/kit/util/conn"
+	"github.com/go-kit/log"
+)
+
+// Instancer yields the ready endpoints of a Kubernetes Service, as listed in
+// its EndpointSlices. The zone, node and pod of every endpoint are published
+// as instance records.
+type Instancer struct {
+	cache     *instance.Cache
+	client    Client
+	logger    log.Logger
+	namespace string
+	service   string
+	port      string
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// NewInstancer returns a Kubernetes instancer that publishes the addresses of
+// the ready endpoints of the service, with the named port. If port is empty,
+// the unnamed port of the service is used, which is the only port of
+// single-port services.
+func NewInstancer(client Client, logger log.Logger, namespace, service, port string) *Instancer {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Instancer{
+		cache:     instance.NewCache(),
+		client:    client,
+		logger:    log.With(logger, "namespace", namespace, "service", service, "port", port),
+		namespace: namespace,
+		service:   service,
+		port:      port,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	slices, resourceVersion, err := s.list()
+	if err == nil {
+		instances, records := makeInstances(sortedSlices(slices), s.port)
+		s.logger.Log("instances", len(instances))
+		s.cache.Update(sd.Event{Instances: instances, Records: records})
+	} else {
+		s.logger.Log("err", err)
+		s.cache.Update(sd.Event{Err: err})
+	}
+
+	go s.loop(slices, resourceVersion, err)
+	return s
+}
+
+// Stop terminates the instancer.
+func (s *Instancer) Stop() {
+	s.cancel()
+}
+
+// minWatchTime is how long a watch must stay open, unless it delivers
+// changes, for the retry backoff to start over.
+const minWatchTime = 10 * time.Second
+
+// loop keeps the slices up to date, by watching them from the resource
+// version of the last change. The slices are only listed again if the watch
+// fails, e.g. because the resource version is too old.
+func (s *Instancer) loop(slices map[string]EndpointSlice, resourceVersion string, err error) {
+	d := 10 * time.Millisecond
+	for {
+		if err == nil {
+			var (
+				begin   = time.Now()
+				changed bool
+			)
+			err = s.client.WatchEndpointSlices(s.ctx, s.namespace, s.service, resourceVersion, func(event WatchEvent) {
+				changed = true
+				if event.Slice.Metadata.ResourceVersion != "" {
+					resourceVersion = event.Slice.Metadata.ResourceVersion
+				}
+				if event.Type == "DELETED" {
+					delete(slices, event.Slice.Metadata.Name)
+				} else {
+					slices[event.Slice.Metadata.Name] = event.Slice
+				}
+				instances, records := makeInstances(sortedSlices(slices), s.port)
+				s.cache.Update(sd.Event{Instances: instances, Records: records})
+			})
+			if s.ctx.Err() != nil {
+				return // stopped
+			}
+			if changed || time.Since(begin) >= minWatchTime {
+				d = 10 * time.Millisecond
+			}
+			if err != nil {
+				// Watches fail routinely, e.g. when the resource version is
+				// too old, so only list errors are published.
+				s.logger.Log("err", err)
+			}
+		}
+
+		// Watches that fail, or close, right away are retried with backoff,
+		// so as not to hammer the API server.
+		select {
+		case <-time.After(d):
+		case <-s.ctx.Done():
+			return
+		}
+		d = conn.Exponential(d)
+
+		if err == nil {
+			continue // the server closed the watch, resume it
+		}
+		slices, resourceVersion, err = s.list()
+		if s.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.cache.Update(sd.Event{Err: err})
+			continue
+		}
+		instances, records := makeInstances(sortedSlices(slices), s.port)
+		s.cache.Update(sd.Event{Instances: instances, Records: records})
+	}
+}
+
+// list returns the slices of the service by name, and the resource version of
+// the list.
+func (s *Instancer) list() (map[string]EndpointSlice, string, error) {
+	list, resourceVersion, err := s.client.EndpointSlices(s.ctx, s.namespace, s.service)
+	if err != nil {
+		return nil, "", err
+	}
+	slices := make(map[string]EndpointSlice, len(list))
+	for _, slice := range list {
+		slices[slice.Metadata.Name] = slice
+	}
+	return slices, resourceVersion, nil
+}
+
+// sortedSlices returns the slices ordered by name, so that instances are
+// published in a stable order.
+func sortedSlices(slices map[string]EndpointSlice) []EndpointSlice {
+	sorted := make([]EndpointSlice, 0, len(slices))
+	for _, slice := range slices {
+		sorted = append(sorted, slice)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Metadata.Name < sorted[j].Metadata.Name })
+	return sorted
+}
+
+// Register implements Instancer.
+func (s *Instancer) Register(ch chan<- sd.Event) {
+	s.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (s *Instancer) Deregister(ch chan<- sd.Event) {
+	s.cache.Deregister(ch)
+}
+
+// makeInstances returns an instance for every address of the ready endpoints
+// in slices exposing the named port. Endpoints are deduplicated, since they may
+// appear in more than one slice while slices are being updated.
+func makeInstances(slices []EndpointSlice, port string) ([]string, []sd.InstanceRecord) {
+	var (
+		instances []string
+		records   []sd.InstanceRecord
+		seen      = map[string]bool{}
+	)
+	for _, slice := range slices {
+		number, ok := findPort(slice.Ports, port)
+		if !ok {
+			continue
+		}
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue // unknown readiness counts as ready
+			}
+			for _, address := range endpoint.Addresses {
+				instance := net.JoinHostPort(address, strconv.Itoa(int(number)))
+				if seen[instance] {
+					continue
+				}
+				seen[instance] = true
+				instances = append(instances, instance)
+				records = append(records, makeRecord(instance, endpoint))
+			}
+		}
+	}
+	return instances, records
+}
+
+func findPort(ports []EndpointPort, name string) (int32, bool) {
+	for _, p := range ports {
+		var pname string
+		if p.Name != nil {
+			pname = *p.Name
+		}
+		if pname == name && p.Port != nil {
+			return *p.Port, true
+		}
+	}
+	return 0, false
+}
+
+func makeRecord(instance string, endpoint Endpoint) sd.InstanceRecord {
+	record := sd.InstanceRecord{Instance: instance}
+	if endpoint.Zone != nil {
+		record.Zone = *endpoint.Zone
+	}
+	meta := map[string]string{}
+	if endpoint.NodeName != nil {
+		meta["node"] = *endpoint.NodeName
+	}
+	if endpoint.Hostname != nil {
+		meta["hostname"] = *endpoint.Hostname
+	}
+	if ref := endpoint.TargetRef; ref != nil && ref.Kind == "Pod" {
+		meta["pod"] = ref.Name
+	}
+	if len(meta) > 0 {
+		record.Meta = meta
+	}
+	return record
+}
@@ -0,0 +1,270 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	client := newTestClient(
+		endpointSlice("web-1", "http", 8080,
+			endpoint("10.0.0.1", "a", nil),
+			endpoint("10.0.0.2", "b", ready(false)),
+		),
+		endpointSlice("web-2", "http", 8080,
+			endpoint("10.0.0.3", "b", ready(true)),
+			endpoint("10.0.0.1", "a", nil), // duplicate
+		),
+		endpointSlice("web-3", "admin", 9090,
+			endpoint("10.0.0.4", "a", nil),
+		),
+	)
+
+	s := NewInstancer(client, log.NewNopLogger(), "default", "web", "http")
+	defer s.Stop()
+
+	state := s.cache.State()
+	if want, have := []string{"10.0.0.1:8080", "10.0.0.3:8080"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	wantRecords := []sd.InstanceRecord{
+		{Instance: "10.0.0.1:8080", Zone: "a", Meta: map[string]string{"node": "node-a", "pod": "pod-10.0.0.1"}},
+		{Instance: "10.0.0.3:8080", Zone: "b", Meta: map[string]string{"node": "node-b", "pod": "pod-10.0.0.3"}},
+	}
+	if want, have := wantRecords, state.Records; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	events := make(chan sd.Event, 1)
+	s.Register(events)
+	defer s.Deregister(events)
+	<-events
+
+	// An endpoint becomes ready, and a slice goes away. The watch delivers
+	// both changes, without listing again.
+	client.update([]EndpointSlice{endpointSlice("web-1", "http", 8080,
+		endpoint("10.0.0.1", "a", nil),
+		endpoint("10.0.0.2", "b", ready(true)),
+	)}, "web-2")
+	for _, want := range [][]string{
+		{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.3:8080"},
+		{"10.0.0.1:8080", "10.0.0.2:8080"},
+	} {
+		select {
+		case event := <-events:
+			if have := event.Instances; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+	}
+	client.update([]EndpointSlice{endpointSlice("web-4", "http", 8080, endpoint("10.0.0.5", "a", nil))})
+	select {
+	case event := <-events:
+		if want, have := []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.5:8080"}, event.Instances; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for update")
+	}
+	client.mtx.Lock()
+	if want, have := 1, client.lists; want != have {
+		t.Errorf("want %d list, have %d", want, have)
+	}
+	client.mtx.Unlock()
+
+	// The API server fails.
+	client.fail(errors.New("unavailable"))
+	select {
+	case event := <-events:
+		if event.Err == nil {
+			t.Errorf("want error, have %v", event.Instances)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for error")
+	}
+}
+
+func TestInstancerWatchBackoff(t *testing.T) {
+	client := newTestClient(endpointSlice("web-1", "http", 8080, endpoint("10.0.0.1", "a", nil)))
+	client.watchErr = errors.New("endpointslices is forbidden")
+
+	s := NewInstancer(client, log.NewNopLogger(), "default", "web", "http")
+	time.Sleep(300 * time.Millisecond)
+	s.Stop()
+
+	// Successful lists don't reset the backoff of failing watches: retrying
+	// every 10ms would list about 30 times.
+	client.mtx.Lock()
+	defer client.mtx.Unlock()
+	if client.lists > 12 {
+		t.Errorf("want backoff, have %d lists in 300ms", client.lists)
+	}
+}
+
+func TestInstancerUnnamedPort(t *testing.T) {
+	client := newTestClient(endpointSlice("web-1", "", 80, endpoint("10.0.0.1", "", nil)))
+	s := NewInstancer(client, log.NewNopLogger(), "default", "web", "")
+	defer s.Stop()
+
+	state := s.cache.State()
+	if want, have := []string{"10.0.0.1:80"}, state.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+type testClient struct {
+	mtx     sync.Mutex
+	slices  []EndpointSlice
+	events  []WatchEvent // the event of version n is events[n-1]
+	err     error
+	changed chan struct{}
+
+	lists      int
+	watchErr   error
+	conditions map[string]bool
+}
+
+func newTestClient(slices ...EndpointSlice) *testClient {
+	return &testClient{slices: slices, changed: make(chan struct{})}
+}
+
+func (c *testClient) EndpointSlices(ctx context.Context, namespace, service string) ([]EndpointSlice, string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.lists++
+	if c.err != nil {
+		return nil, "", c.err
+	}
+	return c.slices, c.resourceVersion(), nil
+}
+
+func (c *testClient) WatchEndpointSlices(ctx context.Context, namespace, service, resourceVersion string, f func(WatchEvent)) error {
+	version, err := strconv.Atoi(resourceVersion)
+	if err != nil {
+		return err
+	}
+	for {
+		c.mtx.Lock()
+		events, changed, err := c.events[version:], c.changed, c.err
+		if c.watchErr != nil {
+			err = c.watchErr
+		}
+		c.mtx.Unlock()
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			f(event)
+			version++
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *testClient) SetPodCondition(ctx context.Context, namespace, pod, conditionType string, status bool) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	if c.conditions == nil {
+		c.conditions = map[string]bool{}
+	}
+	c.conditions[namespace+"/"+pod+"/"+conditionType] = status
+	return nil
+}
+
+// update adds or modifies the slices, and deletes those named in deleted.
+func (c *testClient) update(slices []EndpointSlice, deleted ...string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	current := map[string]int{}
+	for i, slice := range c.slices {
+		current[slice.Metadata.Name] = i
+	}
+	for _, slice := range slices {
+		event := WatchEvent{Type: "MODIFIED", Slice: slice}
+		if i, ok := current[slice.Metadata.Name]; ok {
+			c.slices[i] = slice
+		} else {
+			event.Type = "ADDED"
+			c.slices = append(c.slices, slice)
+		}
+		c.addEvent(event)
+	}
+	for _, name := range deleted {
+		for i, slice := range c.slices {
+			if slice.Metadata.Name == name {
+				c.slices = append(c.slices[:i], c.slices[i+1:]...)
+				c.addEvent(WatchEvent{Type: "DELETED", Slice: slice})
+				break
+			}
+		}
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *testClient) fail(err error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.err = err
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// resourceVersion must be called with the mutex held.
+func (c *testClient) resourceVersion() string {
+	return strconv.Itoa(len(c.events))
+}
+
+// addEvent must be called with the mutex held.
+func (c *testClient) addEvent(event WatchEvent) {
+	c.events = append(c.events, event)
+	c.events[len(c.events)-1].Slice.Metadata.ResourceVersion = c.resourceVersion()
+}
+
+func endpointSlice(name, port string, number int32, endpoints ...Endpoint) EndpointSlice {
+	slice := EndpointSlice{
+		Metadata:    ObjectMeta{Name: name, Labels: map[string]string{ServiceNameLabel: "web"}},
+		AddressType: "IPv4",
+		Endpoints:   endpoints,
+		Ports:       []EndpointPort{{Port: &number}},
+	}
+	if port != "" {
+		slice.Ports[0].Name = &port
+	}
+	return slice
+}
+
+func endpoint(address, zone string, ready *bool) Endpoint {
+	e := Endpoint{
+		Addresses:  []string{address},
+		Conditions: EndpointConditions{Ready: ready},
+	}
+	if zone != "" {
+		node := "node-" + zone
+		e.Zone, e.NodeName = &zone, &node
+		e.TargetRef = &ObjectReference{Kind: "Pod", Name: "pod-" + address}
+	}
+	return e
+}
+
+func ready(b bool) *bool { return &b }
@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/log"
+)
+
+// Registrar controls whether Kubernetes routes traffic to a pod, by setting the
+// status of a custom pod condition. Kubernetes otherwise registers pods by
+// itself, according to their readiness probes; the condition only has an
+// effect if it's listed in the readinessGates of the pod spec, and the service
+// account of the pod is allowed to patch pods/status.
+type Registrar struct {
+	client        Client
+	namespace     string
+	pod           string
+	conditionType string
+	logger        log.Logger
+}
+
+// NewRegistrar returns a Kubernetes Registrar for the named pod, usually the
+// one the program runs in, as exposed by the downward API.
+func NewRegistrar(client Client, namespace, pod, conditionType string, logger log.Logger) *Registrar {
+	return &Registrar{
+		client:        client,
+		namespace:     namespace,
+		pod:           pod,
+		conditionType: conditionType,
+		logger:        log.With(logger, "namespace", namespace, "pod", pod, "condition", conditionType),
+	}
+}
+
+// Register implements sd.Registrar interface.
+func (p *Registrar) Register() {
+	if err := p.setCondition(true); err != nil {
+		p.logger.Log("err", err)
+	} else {
+		p.logger.Log("action", "register")
+	}
+}
+
+// Deregister implements sd.Registrar interface.
+func (p *Registrar) Deregister() {
+	if err := p.setCondition(false); err != nil {
+		p.logger.Log("err", err)
+	} else {
+		p.logger.Log("action", "deregister")
+	}
+}
+
+func (p *Registrar) setCondition(status bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return p.client.SetPodCondition(ctx, p.namespace, p.pod, p.conditionType, status)
+}
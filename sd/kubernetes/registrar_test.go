@@ -0,0 +1,28 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Registrar = (*Registrar)(nil) // API check
+
+func TestRegistrar(t *testing.T) {
+	var (
+		client    = newTestClient()
+		registrar = NewRegistrar(client, "default", "web-0", "example.com/registered", log.NewNopLogger())
+		key       = "default/web-0/example.com/registered"
+	)
+
+	registrar.Register()
+	if want, have := true, client.conditions[key]; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	registrar.Deregister()
+	if status, ok := client.conditions[key]; !ok || status {
+		t.Errorf("want false, have %v (set: %v)", status, ok)
+	}
+}
@@ -1,6 +1,7 @@
 package lb
 
 import (
+	"context"
 	"errors"
 
 	"github.com/go-kit/kit/endpoint"
@@ -11,5 +12,23 @@ type Balancer interface {
 	Endpoint() (endpoint.Endpoint, error)
 }
 
+// RequestBalancer yields endpoints according to some heuristic that takes the
+// request into account, e.g. to route related requests to the same instance.
+type RequestBalancer interface {
+	RequestEndpoint(ctx context.Context, request interface{}) (endpoint.Endpoint, error)
+}
+
+// Route returns an endpoint that forwards every request to the endpoint
+// yielded for it by the RequestBalancer.
+func Route(b RequestBalancer) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		e, err := b.RequestEndpoint(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		return e(ctx, request)
+	}
+}
+
 // ErrNoEndpoints is returned when no qualifying endpoints are available.
 var ErrNoEndpoints = errors.New("no endpoints available")
@@ -0,0 +1,88 @@
+package lb
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// KeyFunc extracts the key used to route a request, e.g. a user ID or a cache
+// shard name, from the request and its context.
+type KeyFunc func(ctx context.Context, request interface{}) string
+
+// NewConsistentHash returns a load balancer that routes requests with the same
+// key to the same instance, using rendezvous hashing over the instance
+// strings. When instances are added or removed, only the keys that were routed
+// to removed instances, or that are now routed to new instances, change
+// instance.
+func NewConsistentHash(s sd.InstanceEndpointer, key KeyFunc) RequestBalancer {
+	return &consistentHash{
+		s:   s,
+		key: key,
+	}
+}
+
+type consistentHash struct {
+	s   sd.InstanceEndpointer
+	key KeyFunc
+
+	mtx    sync.Mutex
+	last   []sd.InstanceEndpoint
+	hashes []uint64
+}
+
+func (c *consistentHash) RequestEndpoint(ctx context.Context, request interface{}) (endpoint.Endpoint, error) {
+	instanceEndpoints, err := c.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(instanceEndpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	hashes := c.instanceHashes(instanceEndpoints)
+	k := hashString(c.key(ctx, request))
+	best, bestScore := 0, uint64(0)
+	for i, h := range hashes {
+		if score := mix64(k ^ h); score > bestScore || i == 0 {
+			best, bestScore = i, score
+		}
+	}
+	return instanceEndpoints[best].Endpoint, nil
+}
+
+// instanceHashes returns the hashes of the instance strings, recomputing them
+// only when the Endpointer yields a different set of endpoints.
+func (c *consistentHash) instanceHashes(instanceEndpoints []sd.InstanceEndpoint) []uint64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.last) != len(instanceEndpoints) || &c.last[0] != &instanceEndpoints[0] {
+		hashes := make([]uint64, len(instanceEndpoints))
+		for i, ie := range instanceEndpoints {
+			hashes[i] = hashString(ie.Instance)
+		}
+		c.last, c.hashes = instanceEndpoints, hashes
+	}
+	return c.hashes
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// mix64 is the finalizer of the SplitMix64 generator. It spreads the bits of
+// the combined key and instance hashes, so scores are evenly distributed.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,91 @@
+package lb_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+)
+
+func TestConsistentHash(t *testing.T) {
+	var (
+		instances = []string{"a:80", "b:80", "c:80", "d:80", "e:80"}
+		keys      = 10000
+		key       = func(_ context.Context, request interface{}) string { return request.(string) }
+	)
+
+	route := func(instances []string) map[string]string {
+		balancer := lb.NewConsistentHash(fixedInstances(instances...), key)
+		routes := map[string]string{}
+		for i := 0; i < keys; i++ {
+			k := fmt.Sprintf("key-%d", i)
+			e, err := balancer.RequestEndpoint(context.Background(), k)
+			if err != nil {
+				t.Fatal(err)
+			}
+			instance, _ := e(context.Background(), k)
+			routes[k] = instance.(string)
+		}
+		return routes
+	}
+
+	before := route(instances)
+
+	// Keys should be spread evenly.
+	counts := map[string]int{}
+	for _, instance := range before {
+		counts[instance]++
+	}
+	for _, instance := range instances {
+		if want, have := keys/len(instances), counts[instance]; have < want*8/10 || have > want*12/10 {
+			t.Errorf("%s: want about %d keys, have %d", instance, want, have)
+		}
+	}
+
+	// Removing an instance should only remap the keys routed to it.
+	after := route(append([]string{}, instances[1:]...))
+	for k, instance := range before {
+		if instance != instances[0] && after[k] != instance {
+			t.Fatalf("%s: moved from %s to %s", k, instance, after[k])
+		}
+	}
+}
+
+func TestConsistentHashNoEndpoints(t *testing.T) {
+	balancer := lb.NewConsistentHash(sd.FixedInstanceEndpointer{}, func(context.Context, interface{}) string { return "" })
+	if _, err := balancer.RequestEndpoint(context.Background(), struct{}{}); err != lb.ErrNoEndpoints {
+		t.Errorf("want %v, have %v", lb.ErrNoEndpoints, err)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	var (
+		balancer = lb.NewConsistentHash(fixedInstances("a", "b"), func(context.Context, interface{}) string { return "k" })
+		e        = lb.Route(balancer)
+	)
+	first, err := e(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if have, _ := e(context.Background(), struct{}{}); first != have {
+			t.Errorf("want %v, have %v", first, have)
+		}
+	}
+}
+
+// fixedInstances returns an InstanceEndpointer whose endpoints respond with
+// their instance string.
+func fixedInstances(instances ...string) sd.FixedInstanceEndpointer {
+	s := make(sd.FixedInstanceEndpointer, len(instances))
+	for i, instance := range instances {
+		instance := instance
+		s[i] = sd.InstanceEndpoint{
+			InstanceRecord: sd.InstanceRecord{Instance: instance},
+			Endpoint:       func(context.Context, interface{}) (interface{}, error) { return instance, nil },
+		}
+	}
+	return s
+}
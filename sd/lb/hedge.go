@@ -0,0 +1,230 @@
+package lb
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+)
+
+// HedgeOption sets an optional parameter for hedged endpoints.
+type HedgeOption func(*hedger)
+
+// HedgeMaxRequests sets the maximum number of requests, including the
+// original one, that may be in flight for a single call. By default, at most
+// one hedged request is sent, i.e. max is 2. Values below 1 are treated as 1,
+// which disables hedging.
+func HedgeMaxRequests(max int) HedgeOption {
+	return func(h *hedger) { h.max = max }
+}
+
+// HedgePercentile derives the hedging delay from observed latencies: the
+// delay is the p-th percentile (0 < p <= 100) of the latencies of the last
+// window successful calls, measured from the original request. Until window
+// latencies have been observed, the fixed delay passed to Hedge is used. If p
+// is out of range, or window is not positive, the fixed delay is always used.
+func HedgePercentile(p float64, window int) HedgeOption {
+	return func(h *hedger) {
+		if p <= 0 || p > 100 || window <= 0 {
+			return
+		}
+		h.percentile = p
+		h.latencies = make([]time.Duration, 0, window)
+	}
+}
+
+// HedgeFiredCounter sets a counter that is incremented every time a hedged
+// request is sent. By default, nothing is counted.
+func HedgeFiredCounter(c metrics.Counter) HedgeOption {
+	return func(h *hedger) { h.fired = c }
+}
+
+// HedgeWonCounter sets a counter that is incremented every time a hedged
+// request, rather than the original one, provides the response. By default,
+// nothing is counted.
+func HedgeWonCounter(c metrics.Counter) HedgeOption {
+	return func(h *hedger) { h.won = c }
+}
+
+// Hedge wraps a service load balancer and returns an endpoint oriented load
+// balancer that reduces tail latency by hedging. Every request is sent to an
+// endpoint from the load balancer; if it hasn't answered after delay, the
+// same request is sent to the next endpoint from the load balancer, and so
+// on, up to the maximum number of requests set by HedgeMaxRequests. A failed
+// request triggers the next hedged request immediately. The first successful
+// response is returned and all other requests are canceled. If all requests
+// fail, the last error is returned.
+//
+// Hedged requests go to whichever endpoint the load balancer yields next.
+// Hedge can't tell endpoints apart, so load balancers that may yield the same
+// endpoint twice in a row, like the one returned by NewRandom, may send a
+// hedged request to an endpoint the call is already waiting for. With
+// NewRoundRobin, the requests of a call go to distinct endpoints, as long as
+// there are at least as many as the maximum number of requests. Only
+// idempotent requests should be hedged.
+func Hedge(delay time.Duration, b Balancer, options ...HedgeOption) endpoint.Endpoint {
+	if b == nil {
+		panic("nil Balancer")
+	}
+	h := &hedger{
+		b:     b,
+		delay: delay,
+		max:   2,
+		fired: discard.NewCounter(),
+		won:   discard.NewCounter(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if h.max < 1 {
+		h.max = 1
+	}
+	return h.endpoint
+}
+
+type hedger struct {
+	b          Balancer
+	delay      time.Duration
+	max        int
+	fired      metrics.Counter
+	won        metrics.Counter
+	percentile float64
+
+	mtx       sync.Mutex
+	latencies []time.Duration // ring buffer, only used with HedgePercentile
+	next      int
+}
+
+type hedgeResult struct {
+	n        int
+	response interface{}
+	err      error
+}
+
+func (h *hedger) endpoint(ctx context.Context, request interface{}) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // cancels all outstanding requests
+
+	var (
+		begin   = time.Now()
+		results = make(chan hedgeResult, h.max)
+		sent    int
+		pending int
+		lastErr error
+		delay   = h.hedgeDelay()
+		timer   = time.NewTimer(delay)
+	)
+	defer timer.Stop()
+
+	send := func() {
+		// The next hedged request is due after delay from now.
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+
+		e, err := h.b.Endpoint()
+		n := sent
+		sent++
+		if err != nil {
+			lastErr = err
+			return
+		}
+		if n > 0 {
+			h.fired.Add(1)
+		}
+		pending++
+		go func() {
+			response, err := e(ctx, request)
+			results <- hedgeResult{n, response, err}
+		}()
+	}
+
+	send()
+	for {
+		for pending == 0 && sent < h.max {
+			send() // nothing in flight, don't wait
+		}
+		if pending == 0 {
+			return nil, lastErr
+		}
+
+		var hedge <-chan time.Time
+		if sent < h.max {
+			hedge = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-hedge:
+			send()
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				h.observe(time.Since(begin))
+				if r.n > 0 {
+					h.won.Add(1)
+				}
+				return r.response, nil
+			}
+			lastErr = r.err
+			if sent < h.max {
+				send()
+			}
+		}
+	}
+}
+
+func (h *hedger) hedgeDelay() time.Duration {
+	if h.percentile <= 0 {
+		return h.delay
+	}
+
+	h.mtx.Lock()
+	if len(h.latencies) < cap(h.latencies) || len(h.latencies) == 0 {
+		h.mtx.Unlock()
+		return h.delay
+	}
+	latencies := make([]time.Duration, len(h.latencies))
+	copy(latencies, h.latencies)
+	h.mtx.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	idx := int(math.Ceil(h.percentile/100*float64(len(latencies)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(latencies) {
+		idx = len(latencies) - 1
+	}
+	return latencies[idx]
+}
+
+func (h *hedger) observe(took time.Duration) {
+	if h.percentile <= 0 {
+		return
+	}
+
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if len(h.latencies) < cap(h.latencies) {
+		h.latencies = append(h.latencies, took)
+		return
+	}
+	if len(h.latencies) == 0 {
+		return
+	}
+	h.latencies[h.next] = took
+	h.next = (h.next + 1) % len(h.latencies)
+}
@@ -0,0 +1,198 @@
+package lb_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+)
+
+func TestHedgeFirstWins(t *testing.T) {
+	var (
+		fired = generic.NewCounter("fired")
+		fast  = func(context.Context, interface{}) (interface{}, error) { return "fast", nil }
+		hedge = lb.Hedge(time.Second, lb.NewRoundRobin(sd.FixedEndpointer{fast}), lb.HedgeFiredCounter(fired))
+	)
+	response, err := hedge(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "fast", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 0.0, fired.Value(); want != have {
+		t.Errorf("want %v hedges, have %v", want, have)
+	}
+}
+
+func TestHedgeSlowEndpoint(t *testing.T) {
+	var (
+		canceled = make(chan struct{})
+		slow     = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			close(canceled)
+			return nil, ctx.Err()
+		}
+		fast  = func(context.Context, interface{}) (interface{}, error) { return "fast", nil }
+		fired = generic.NewCounter("fired")
+		won   = generic.NewCounter("won")
+		hedge = lb.Hedge(
+			time.Millisecond,
+			lb.NewRoundRobin(sd.FixedEndpointer{slow, fast}),
+			lb.HedgeFiredCounter(fired),
+			lb.HedgeWonCounter(won),
+		)
+	)
+	response, err := hedge(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "fast", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := 1.0, fired.Value(); want != have {
+		t.Errorf("want %v hedges, have %v", want, have)
+	}
+	if want, have := 1.0, won.Value(); want != have {
+		t.Errorf("want %v hedges won, have %v", want, have)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("slow request wasn't canceled")
+	}
+}
+
+func TestHedgeErrorTriggersHedge(t *testing.T) {
+	var (
+		failing = func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("fail") }
+		ok      = func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+		hedge   = lb.Hedge(time.Hour, lb.NewRoundRobin(sd.FixedEndpointer{failing, ok}))
+	)
+	response, err := hedge(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "ok", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestHedgeAllFail(t *testing.T) {
+	var (
+		myErr     = errors.New("fail")
+		failing   = func(context.Context, interface{}) (interface{}, error) { return nil, myErr }
+		endpoints = sd.FixedEndpointer{failing, failing, failing}
+		hedge     = lb.Hedge(time.Millisecond, lb.NewRoundRobin(endpoints), lb.HedgeMaxRequests(3))
+	)
+	if _, err := hedge(context.Background(), struct{}{}); err != myErr {
+		t.Errorf("want %v, have %v", myErr, err)
+	}
+}
+
+func TestHedgeMaxRequestsClamped(t *testing.T) {
+	var (
+		fired = generic.NewCounter("fired")
+		calls int64
+		slow  = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			atomic.AddInt64(&calls, 1)
+			time.Sleep(10 * time.Millisecond)
+			return "slow", nil
+		}
+		hedge = lb.Hedge(time.Millisecond, lb.NewRoundRobin(sd.FixedEndpointer{slow, slow}), lb.HedgeMaxRequests(0), lb.HedgeFiredCounter(fired))
+	)
+	response, err := hedge(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "slow", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int64(1), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+	if want, have := 0.0, fired.Value(); want != have {
+		t.Errorf("want %v hedges, have %v", want, have)
+	}
+}
+
+func TestHedgeNoEndpoints(t *testing.T) {
+	hedge := lb.Hedge(time.Millisecond, lb.NewRoundRobin(sd.FixedEndpointer{}))
+	if _, err := hedge(context.Background(), struct{}{}); err != lb.ErrNoEndpoints {
+		t.Errorf("want %v, have %v", lb.ErrNoEndpoints, err)
+	}
+}
+
+func TestHedgePercentile(t *testing.T) {
+	var (
+		slow  = make(chan bool, 1)
+		a     = func(ctx context.Context, _ interface{}) (interface{}, error) { return wait(ctx, slow) }
+		b     = func(ctx context.Context, _ interface{}) (interface{}, error) { return "b", nil }
+		hedge = lb.Hedge(time.Hour, lb.NewRoundRobin(sd.FixedEndpointer{a, b}), lb.HedgePercentile(99, 10))
+	)
+	for i := 0; i < 10; i++ { // warm up with fast responses
+		slow <- false
+		if _, err := hedge(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+		<-slow
+	}
+
+	// The next request goes to a, which is now slow. With the fixed delay of
+	// an hour, it would time out; the observed latencies should make it hedge
+	// to b almost immediately.
+	slow <- true
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	response, err := hedge(ctx, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "b", response; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestHedgePercentileInvalid(t *testing.T) {
+	var (
+		slow = func(ctx context.Context, _ interface{}) (interface{}, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		fast = func(context.Context, interface{}) (interface{}, error) { return "fast", nil }
+	)
+	for _, option := range []lb.HedgeOption{
+		lb.HedgePercentile(99, -1),
+		lb.HedgePercentile(99, 0),
+		lb.HedgePercentile(0, 10),
+		lb.HedgePercentile(101, 10),
+	} {
+		// The fixed delay is used.
+		hedge := lb.Hedge(time.Millisecond, lb.NewRoundRobin(sd.FixedEndpointer{slow, fast}), option)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		response, err := hedge(ctx, struct{}{})
+		cancel()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "fast", response; want != have {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+}
+
+// wait peeks at slow: if true, it blocks until ctx is done.
+func wait(ctx context.Context, slow chan bool) (interface{}, error) {
+	s := <-slow
+	slow <- s
+	if !s {
+		return "a", nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
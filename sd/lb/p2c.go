@@ -0,0 +1,177 @@
+package lb
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// p2cDecay is the time constant of the latency moving average: an
+// observation contributes about 63% of the average after this long.
+const p2cDecay = 10 * time.Second
+
+// p2cPenalty is the latency assumed for endpoints that have requests in
+// flight, but haven't responded yet.
+const p2cPenalty = time.Second
+
+// P2COption sets an optional parameter for P2C load balancers.
+type P2COption func(*p2c)
+
+// P2CClock makes the balancer use the given function to tell the time, when
+// measuring latencies. By default, time.Now is used.
+func P2CClock(now func() time.Time) P2COption {
+	return func(p *p2c) { p.now = now }
+}
+
+// NewP2C returns a load balancer that picks two endpoints at random, and
+// returns the less loaded one, according to the number of requests in flight
+// and a moving average of the response latency. Endpoints yielded by the
+// balancer track the load of their instance, and must be invoked for the
+// statistics to be meaningful.
+//
+// Statistics are kept by instance string, so they survive updates of the
+// Instancer for the instances that remain; instances that go away are
+// forgotten.
+func NewP2C(s sd.InstanceEndpointer, seed int64, options ...P2COption) Balancer {
+	p := &p2c{
+		s:     s,
+		r:     rand.New(rand.NewSource(seed)),
+		now:   time.Now,
+		loads: map[string]*load{},
+	}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+type p2c struct {
+	s   sd.InstanceEndpointer
+	now func() time.Time
+
+	mtx     sync.Mutex
+	r       *rand.Rand
+	last    []sd.InstanceEndpoint
+	tracked []loadEndpoint
+	loads   map[string]*load // by instance
+}
+
+func (p *p2c) Endpoint() (endpoint.Endpoint, error) {
+	instanceEndpoints, err := p.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(instanceEndpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	// Endpointers, in particular sd.DefaultEndpointer, return the same slice
+	// until the set of endpoints changes.
+	if len(p.last) != len(instanceEndpoints) || &p.last[0] != &instanceEndpoints[0] {
+		p.update(instanceEndpoints)
+	}
+
+	if len(p.tracked) == 1 {
+		return p.tracked[0].Endpoint, nil
+	}
+
+	i := p.r.Intn(len(p.tracked))
+	j := p.r.Intn(len(p.tracked) - 1)
+	if j >= i {
+		j++ // make sure the candidates are distinct
+	}
+	a, b := p.tracked[i], p.tracked[j]
+	if b.cost() < a.cost() {
+		a = b
+	}
+	return a.Endpoint, nil
+}
+
+// update wraps the new endpoints, keeping the load of the instances that were
+// already known. It must be called with the mutex held.
+func (p *p2c) update(instanceEndpoints []sd.InstanceEndpoint) {
+	var (
+		tracked = make([]loadEndpoint, len(instanceEndpoints))
+		loads   = make(map[string]*load, len(instanceEndpoints))
+	)
+	for i, ie := range instanceEndpoints {
+		l, ok := p.loads[ie.Instance]
+		if !ok {
+			l = &load{now: p.now}
+		}
+		loads[ie.Instance] = l
+		tracked[i] = loadEndpoint{Endpoint: l.track(ie.Endpoint), load: l}
+	}
+	p.last, p.tracked, p.loads = instanceEndpoints, tracked, loads
+}
+
+// loadEndpoint is an endpoint that tracks the load of its instance.
+type loadEndpoint struct {
+	endpoint.Endpoint
+	*load
+}
+
+// load is the load of an instance.
+type load struct {
+	now func() time.Time
+
+	mtx      sync.Mutex
+	inflight int
+	ewma     float64 // nanoseconds
+	stamp    time.Time
+}
+
+// track wraps the endpoint, so its requests count towards the load.
+func (l *load) track(next endpoint.Endpoint) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		l.mtx.Lock()
+		l.inflight++
+		l.mtx.Unlock()
+
+		begin := l.now()
+		response, err := next(ctx, request)
+		end := l.now()
+
+		l.mtx.Lock()
+		l.inflight--
+		l.observe(end, end.Sub(begin))
+		l.mtx.Unlock()
+
+		return response, err
+	}
+}
+
+// observe must be called with the mutex held.
+func (l *load) observe(now time.Time, rtt time.Duration) {
+	if l.stamp.IsZero() {
+		l.ewma, l.stamp = float64(rtt), now
+		return
+	}
+	elapsed := now.Sub(l.stamp)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	w := math.Exp(-float64(elapsed) / float64(p2cDecay))
+	l.ewma = l.ewma*w + float64(rtt)*(1-w)
+	l.stamp = now
+}
+
+// cost is the expected latency of a new request, weighted by the requests
+// already in flight. Idle endpoints without observations cost nothing, so new
+// endpoints are tried quickly.
+func (l *load) cost() float64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.stamp.IsZero() {
+		return float64(p2cPenalty) * float64(l.inflight)
+	}
+	return (l.ewma + 1) * float64(l.inflight+1)
+}
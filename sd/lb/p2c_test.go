@@ -0,0 +1,125 @@
+package lb_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+)
+
+func TestP2CPrefersFasterEndpoint(t *testing.T) {
+	var (
+		clock     = time.Now()
+		counts    = make([]int, 3)
+		delays    = []time.Duration{time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+		instances = make(sd.FixedInstanceEndpointer, len(delays))
+	)
+	for i := range delays {
+		i := i
+		instances[i] = sd.InstanceEndpoint{
+			InstanceRecord: sd.InstanceRecord{Instance: string(rune('a' + i))},
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				counts[i]++
+				clock = clock.Add(delays[i])
+				return struct{}{}, nil
+			},
+		}
+	}
+
+	balancer := lb.NewP2C(instances, 12345, lb.P2CClock(func() time.Time { return clock }))
+	for i := 0; i < 1000; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e(context.Background(), struct{}{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The fast endpoint is always picked when it's a candidate, which happens
+	// in two thirds of the cases.
+	if counts[0] < 600 {
+		t.Errorf("fast endpoint picked %d times, want at least 600 (%v)", counts[0], counts)
+	}
+}
+
+func TestP2CPrefersIdleEndpoint(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		started = make(chan struct{}, 1)
+		busy    = sd.InstanceEndpoint{
+			InstanceRecord: sd.InstanceRecord{Instance: "busy"},
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				started <- struct{}{}
+				<-release
+				return "busy", nil
+			},
+		}
+		idle      = fixedInstances("idle")[0]
+		instances = &swappableInstances{s: sd.FixedInstanceEndpointer{busy, idle}}
+	)
+	defer close(release)
+
+	balancer := lb.NewP2C(instances, 1)
+
+	// Keep calling until the busy endpoint has a request in flight.
+	for inflight := false; !inflight; {
+		e, _ := balancer.Endpoint()
+		go e(context.Background(), struct{}{})
+		select {
+		case <-started:
+			inflight = true
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Both endpoints are candidates every time, so the idle one always wins,
+	// even after an update that keeps both instances.
+	for i := 0; i < 20; i++ {
+		if i == 10 {
+			instances.set(sd.FixedInstanceEndpointer{idle, busy})
+		}
+		e, _ := balancer.Endpoint()
+		response, _ := e(context.Background(), struct{}{})
+		if want, have := "idle", response; want != have {
+			t.Fatalf("request %d: want %v, have %v", i, want, have)
+		}
+	}
+}
+
+func TestP2CNoEndpoints(t *testing.T) {
+	balancer := lb.NewP2C(sd.FixedInstanceEndpointer{}, 1)
+	if _, err := balancer.Endpoint(); err != lb.ErrNoEndpoints {
+		t.Errorf("want %v, have %v", lb.ErrNoEndpoints, err)
+	}
+}
+
+// swappableInstances is an InstanceEndpointer whose instances can be
+// replaced, like those of an Endpointer receiving updates.
+type swappableInstances struct {
+	mtx sync.Mutex
+	s   sd.FixedInstanceEndpointer
+}
+
+func (s *swappableInstances) set(instances sd.FixedInstanceEndpointer) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.s = instances
+}
+
+func (s *swappableInstances) Endpoints() ([]endpoint.Endpoint, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.s.Endpoints()
+}
+
+func (s *swappableInstances) InstanceEndpoints() ([]sd.InstanceEndpoint, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.s.InstanceEndpoints()
+}
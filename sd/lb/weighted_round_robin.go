@@ -0,0 +1,81 @@
+package lb
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// NewWeightedRoundRobin returns a load balancer that returns services in
+// sequence, proportionally to the weights of their instance records. It uses
+// the smooth weighted round-robin algorithm, so heavier instances are spread
+// out over the sequence rather than picked in bursts. Instances without a
+// weight count as having weight 1.
+func NewWeightedRoundRobin(s sd.InstanceEndpointer) Balancer {
+	return &weightedRoundRobin{s: s}
+}
+
+type weightedRoundRobin struct {
+	s sd.InstanceEndpointer
+
+	mtx     sync.Mutex
+	last    []sd.InstanceEndpoint
+	weights []int
+	current []int // by index in last
+	total   int
+}
+
+func (w *weightedRoundRobin) Endpoint() (endpoint.Endpoint, error) {
+	instanceEndpoints, err := w.s.InstanceEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	if len(instanceEndpoints) <= 0 {
+		return nil, ErrNoEndpoints
+	}
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	// Endpointers, in particular sd.DefaultEndpointer, return the same slice
+	// until the set of endpoints changes.
+	if len(w.last) != len(instanceEndpoints) || &w.last[0] != &instanceEndpoints[0] {
+		w.update(instanceEndpoints)
+	}
+
+	best := 0
+	for i, weight := range w.weights {
+		w.current[i] += weight
+		if w.current[i] > w.current[best] {
+			best = i
+		}
+	}
+	w.current[best] -= w.total
+
+	return w.last[best].Endpoint, nil
+}
+
+// update rebuilds the schedule for the new endpoints, keeping the current
+// weights of the instances that were already known, so the sequence carries
+// on smoothly. It must be called with the mutex held.
+func (w *weightedRoundRobin) update(instanceEndpoints []sd.InstanceEndpoint) {
+	known := make(map[string]int, len(w.last))
+	for i, ie := range w.last {
+		known[ie.Instance] = w.current[i]
+	}
+
+	w.last = instanceEndpoints
+	w.weights = make([]int, len(instanceEndpoints))
+	w.current = make([]int, len(instanceEndpoints))
+	w.total = 0
+	for i, ie := range instanceEndpoints {
+		weight := ie.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		w.weights[i] = weight
+		w.current[i] = known[ie.Instance] // instances that went away are forgotten
+		w.total += weight
+	}
+}
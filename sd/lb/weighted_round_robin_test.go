@@ -0,0 +1,72 @@
+package lb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/sd"
+)
+
+func TestWeightedRoundRobin(t *testing.T) {
+	var (
+		counts    = map[string]int{}
+		sequence  []string
+		endpoints = sd.FixedInstanceEndpointer{}
+	)
+	for instance, weight := range map[string]int{"a": 5, "b": 1, "c": 0} {
+		instance := instance
+		endpoints = append(endpoints, sd.InstanceEndpoint{
+			InstanceRecord: sd.InstanceRecord{Instance: instance, Weight: weight},
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				counts[instance]++
+				sequence = append(sequence, instance)
+				return struct{}{}, nil
+			},
+		})
+	}
+
+	balancer := NewWeightedRoundRobin(endpoints)
+	for i := 0; i < 70; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		e(context.Background(), struct{}{})
+	}
+
+	if want, have := map[string]int{"a": 50, "b": 10, "c": 10}, counts; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Smooth: the heavy instance is never picked more than 5 times in a row.
+	run := 0
+	for _, instance := range sequence {
+		if instance != "a" {
+			run = 0
+			continue
+		}
+		if run++; run > 5 {
+			t.Fatalf("a picked %d times in a row: %v", run, sequence)
+		}
+	}
+}
+
+func TestWeightedRoundRobinNoEndpoints(t *testing.T) {
+	balancer := NewWeightedRoundRobin(sd.FixedInstanceEndpointer{})
+	if _, err := balancer.Endpoint(); err != ErrNoEndpoints {
+		t.Errorf("want %v, have %v", ErrNoEndpoints, err)
+	}
+}
+
+func TestWeightedRoundRobinNoAllocs(t *testing.T) {
+	balancer := NewWeightedRoundRobin(sd.FixedInstanceEndpointer{
+		{InstanceRecord: sd.InstanceRecord{Instance: "a", Weight: 2}, Endpoint: func(context.Context, interface{}) (interface{}, error) { return nil, nil }},
+		{InstanceRecord: sd.InstanceRecord{Instance: "b", Weight: 1}, Endpoint: func(context.Context, interface{}) (interface{}, error) { return nil, nil }},
+	})
+	balancer.Endpoint() // build the schedule
+
+	if allocs := testing.AllocsPerRun(100, func() { balancer.Endpoint() }); allocs > 0 {
+		t.Errorf("want no allocations per call, have %v", allocs)
+	}
+}
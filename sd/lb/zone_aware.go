@@ -0,0 +1,108 @@
+package lb
+
+import (
+	"sync"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// ZoneOption sets an optional parameter for zone-aware Endpointers.
+type ZoneOption func(*zoneAware)
+
+// ZoneFunc derives the zone of an instance from its instance string, e.g. by
+// parsing the host name. By default, the Zone of the instance record is used.
+func ZoneFunc(f func(instance string) string) ZoneOption {
+	return func(z *zoneAware) { z.zoneFunc = f }
+}
+
+// ZoneMinEndpoints sets the minimum number of endpoints in the local zone.
+// If fewer are available, e.g. because instances were ejected by outlier
+// detection or failed their health checks, endpoints in other zones are
+// yielded as well. The default is 1, i.e. other zones are only used when the
+// local zone has no endpoints at all.
+func ZoneMinEndpoints(n int) ZoneOption {
+	return func(z *zoneAware) { z.minEndpoints = n }
+}
+
+// NewZoneAware returns an Endpointer that only yields the endpoints of s in
+// the given local zone, as long as there are enough of them, and all
+// endpoints otherwise. It's meant to be wrapped by a Balancer, e.g.
+//
+//	lb.NewRoundRobin(lb.NewZoneAware(endpointer, "us-east-1a"))
+//
+// Instances with an unknown zone are never considered local.
+func NewZoneAware(s sd.InstanceEndpointer, zone string, options ...ZoneOption) sd.InstanceEndpointer {
+	z := &zoneAware{
+		s:            s,
+		zone:         zone,
+		minEndpoints: 1,
+	}
+	for _, option := range options {
+		option(z)
+	}
+	return z
+}
+
+type zoneAware struct {
+	s            sd.InstanceEndpointer
+	zone         string
+	zoneFunc     func(instance string) string
+	minEndpoints int
+
+	mtx       sync.Mutex
+	last      []sd.InstanceEndpoint
+	endpoints []endpoint.Endpoint
+	filtered  []sd.InstanceEndpoint
+}
+
+func (z *zoneAware) Endpoints() ([]endpoint.Endpoint, error) {
+	endpoints, _, err := z.get()
+	return endpoints, err
+}
+
+func (z *zoneAware) InstanceEndpoints() ([]sd.InstanceEndpoint, error) {
+	_, instanceEndpoints, err := z.get()
+	return instanceEndpoints, err
+}
+
+// get returns the preferred endpoints, recomputing them only when the
+// Endpointer yields a different set of endpoints, so the results can be told
+// apart by identity, too.
+func (z *zoneAware) get() ([]endpoint.Endpoint, []sd.InstanceEndpoint, error) {
+	instanceEndpoints, err := z.s.InstanceEndpoints()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	z.mtx.Lock()
+	defer z.mtx.Unlock()
+
+	if len(z.last) == len(instanceEndpoints) && (len(z.last) == 0 || &z.last[0] == &instanceEndpoints[0]) && z.endpoints != nil {
+		return z.endpoints, z.filtered, nil
+	}
+
+	local := make([]sd.InstanceEndpoint, 0, len(instanceEndpoints))
+	for _, ie := range instanceEndpoints {
+		if z.zone != "" && z.zoneOf(ie) == z.zone {
+			local = append(local, ie)
+		}
+	}
+	if len(local) < z.minEndpoints || len(local) == 0 {
+		local = instanceEndpoints // spill over to other zones
+	}
+
+	endpoints := make([]endpoint.Endpoint, len(local))
+	for i, ie := range local {
+		endpoints[i] = ie.Endpoint
+	}
+	z.last, z.endpoints, z.filtered = instanceEndpoints, endpoints, local
+	return endpoints, local, nil
+}
+
+func (z *zoneAware) zoneOf(ie sd.InstanceEndpoint) string {
+	if z.zoneFunc != nil {
+		return z.zoneFunc(ie.Instance)
+	}
+	return ie.Zone
+}
@@ -0,0 +1,85 @@
+package lb_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/lb"
+)
+
+func TestZoneAwarePrefersLocalZone(t *testing.T) {
+	s := zonedInstances(map[string]string{"a": "east", "b": "west", "c": "east"})
+	balancer := lb.NewRoundRobin(lb.NewZoneAware(s, "east"))
+
+	if want, have := []string{"a", "c"}, picks(t, balancer, 10); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestZoneAwareSpillover(t *testing.T) {
+	s := zonedInstances(map[string]string{"a": "east", "b": "west", "c": "north"})
+
+	for _, testcase := range []struct {
+		zone         string
+		minEndpoints int
+		want         []string
+	}{
+		{"east", 1, []string{"a"}},
+		{"east", 2, []string{"a", "b", "c"}},
+		{"south", 1, []string{"a", "b", "c"}},
+		{"", 1, []string{"a", "b", "c"}},
+	} {
+		balancer := lb.NewRandom(lb.NewZoneAware(s, testcase.zone, lb.ZoneMinEndpoints(testcase.minEndpoints)), 1)
+		if want, have := testcase.want, picks(t, balancer, 100); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s/%d: want %v, have %v", testcase.zone, testcase.minEndpoints, want, have)
+		}
+	}
+}
+
+func TestZoneAwareZoneFunc(t *testing.T) {
+	var (
+		s        = fixedInstances("east-1:80", "west-1:80", "east-2:80")
+		zoneFunc = func(instance string) string { return strings.SplitN(instance, "-", 2)[0] }
+		balancer = lb.NewRoundRobin(lb.NewZoneAware(s, "west", lb.ZoneFunc(zoneFunc)))
+	)
+	if want, have := []string{"west-1:80"}, picks(t, balancer, 10); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func zonedInstances(zones map[string]string) sd.FixedInstanceEndpointer {
+	var instances []string
+	for instance := range zones {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	s := fixedInstances(instances...)
+	for i := range s {
+		s[i].Zone = zones[s[i].Instance]
+	}
+	return s
+}
+
+// picks returns the sorted set of instances picked by the balancer in n calls.
+func picks(t *testing.T, balancer lb.Balancer, n int) []string {
+	t.Helper()
+	seen := map[string]bool{}
+	for i := 0; i < n; i++ {
+		e, err := balancer.Endpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		response, _ := e(context.Background(), struct{}{})
+		seen[response.(string)] = true
+	}
+	var instances []string
+	for instance := range seen {
+		instances = append(instances, instance)
+	}
+	sort.Strings(instances)
+	return instances
+}
@@ -0,0 +1,279 @@
+package sd
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/log"
+)
+
+// OutlierConfig controls passive health checking of the endpoints yielded by
+// an Endpointer. Zero values select the documented defaults.
+type OutlierConfig struct {
+	// ConsecutiveFailures is the number of consecutive failed requests after
+	// which an instance is ejected. If neither ConsecutiveFailures nor
+	// FailureRate is set, it defaults to 5.
+	ConsecutiveFailures int
+
+	// FailureRate, between 0 and 1, ejects an instance if the ratio of failed
+	// requests reaches it. The ratio is evaluated, and reset, every
+	// MinRequests requests. By default, the failure rate is not tracked.
+	FailureRate float64
+
+	// MinRequests is the number of requests over which FailureRate is
+	// evaluated. The default is 100.
+	MinRequests int
+
+	// BaseEjectionTime is how long an instance is ejected the first time. It
+	// doubles with every subsequent ejection, up to MaxEjectionTime. The
+	// default is 30 seconds.
+	BaseEjectionTime time.Duration
+
+	// MaxEjectionTime caps the ejection time. An instance that stays healthy
+	// for this long after its last ejection starts over from
+	// BaseEjectionTime. The default is 300 seconds, or BaseEjectionTime if
+	// that's larger.
+	MaxEjectionTime time.Duration
+
+	// MaxEjectionPercent caps the share of instances that may be ejected at
+	// the same time. Instances that would exceed it aren't ejected, except
+	// that one instance may always be ejected, however few instances there
+	// are. The default is 10.
+	MaxEjectionPercent int
+
+	// Ejections, if set, is incremented every time an instance is ejected.
+	Ejections metrics.Counter
+}
+
+// OutlierDetection returns an EndpointerOption that enables passive health
+// checking. The Endpointer tracks the outcome of requests made through the
+// endpoints it yields, and temporarily stops yielding the endpoints of
+// instances that keep failing, as configured. Requests that fail because
+// their context was canceled or timed out don't count as failures.
+// Ejections are logged to the Endpointer's logger.
+func OutlierDetection(config OutlierConfig) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		opts.outlierDetection = true
+		opts.outlierConfig = config
+	}
+}
+
+// HealthWatcher may be implemented by the io.Closer returned by a Factory, if
+// the transport knows the health of the instance out of band, e.g. from the
+// state of its connection. WatchHealth is called once, when the endpoint is
+// created, and the transport calls report whenever the health changes, until
+// it's closed. With OutlierDetection, the Endpointer doesn't yield the
+// endpoints of instances last reported unhealthy; otherwise, reports are
+// ignored.
+type HealthWatcher interface {
+	WatchHealth(report func(healthy bool))
+}
+
+// outlierDetector tracks the health of the instances in an endpointCache.
+type outlierDetector struct {
+	config  OutlierConfig
+	logger  log.Logger
+	timeNow func() time.Time
+
+	mtx        sync.Mutex
+	hosts      map[string]*outlierHost
+	version    uint64    // incremented on every ejection
+	nextReturn time.Time // when the next ejected instance comes back
+
+	// filtered results, valid for the same input, version and until nextReturn
+	lastInput   []InstanceEndpoint
+	lastVersion uint64
+	endpoints   []endpoint.Endpoint
+	filtered    []InstanceEndpoint
+}
+
+type outlierHost struct {
+	consecutive  int
+	requests     int
+	failures     int
+	ejections    int
+	ejectedUntil time.Time
+	down         bool // reported unhealthy by a HealthWatcher
+}
+
+func newOutlierDetector(config OutlierConfig, logger log.Logger, timeNow func() time.Time) *outlierDetector {
+	if config.ConsecutiveFailures <= 0 && config.FailureRate <= 0 {
+		config.ConsecutiveFailures = 5
+	}
+	if config.MinRequests <= 0 {
+		config.MinRequests = 100
+	}
+	if config.BaseEjectionTime <= 0 {
+		config.BaseEjectionTime = 30 * time.Second
+	}
+	if config.MaxEjectionTime <= 0 {
+		config.MaxEjectionTime = 300 * time.Second
+	}
+	if config.MaxEjectionTime < config.BaseEjectionTime {
+		config.MaxEjectionTime = config.BaseEjectionTime
+	}
+	if config.MaxEjectionPercent <= 0 {
+		config.MaxEjectionPercent = 10
+	}
+	if config.Ejections == nil {
+		config.Ejections = discard.NewCounter()
+	}
+	return &outlierDetector{
+		config:  config,
+		logger:  logger,
+		timeNow: timeNow,
+		hosts:   map[string]*outlierHost{},
+	}
+}
+
+// add starts tracking the instance, and returns an endpoint that reports the
+// outcome of requests to the detector. If the closer is a HealthWatcher, its
+// reports are tracked too.
+func (d *outlierDetector) add(instance string, next endpoint.Endpoint, closer io.Closer) endpoint.Endpoint {
+	h := &outlierHost{}
+
+	d.mtx.Lock()
+	d.hosts[instance] = h
+	d.mtx.Unlock()
+
+	if w, ok := closer.(HealthWatcher); ok {
+		w.WatchHealth(func(healthy bool) { d.setHealthy(instance, h, healthy) })
+	}
+
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		response, err := next(ctx, request)
+		if err != nil && ctx.Err() != nil {
+			return response, err // the caller gave up, not the instance
+		}
+		d.record(instance, h, err)
+		return response, err
+	}
+}
+
+// remove stops tracking the instance.
+func (d *outlierDetector) remove(instance string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	delete(d.hosts, instance)
+}
+
+func (d *outlierDetector) setHealthy(instance string, h *outlierHost, healthy bool) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.hosts[instance] != h || h.down == !healthy {
+		return // instance went away, or no change
+	}
+	h.down = !healthy
+	d.version++
+	d.logger.Log("instance", instance, "healthy", healthy)
+}
+
+func (d *outlierDetector) record(instance string, h *outlierHost, err error) {
+	now := d.timeNow()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.hosts[instance] != h || now.Before(h.ejectedUntil) {
+		return // instance went away, or requests in flight while ejected
+	}
+
+	h.requests++
+	if err == nil {
+		h.consecutive = 0
+	} else {
+		h.consecutive++
+		h.failures++
+	}
+
+	var eject bool
+	if d.config.ConsecutiveFailures > 0 && h.consecutive >= d.config.ConsecutiveFailures {
+		eject = true
+	}
+	if d.config.FailureRate > 0 && h.requests >= d.config.MinRequests {
+		if float64(h.failures)/float64(h.requests) >= d.config.FailureRate {
+			eject = true
+		}
+		h.requests, h.failures = 0, 0
+	}
+	if !eject {
+		return
+	}
+
+	var ejected int
+	for _, other := range d.hosts {
+		if now.Before(other.ejectedUntil) {
+			ejected++
+		}
+	}
+	if ejected > 0 && (ejected+1)*100 > len(d.hosts)*d.config.MaxEjectionPercent {
+		return // would exceed MaxEjectionPercent
+	}
+
+	if !h.ejectedUntil.IsZero() && now.Sub(h.ejectedUntil) > d.config.MaxEjectionTime {
+		h.ejections = 0 // healthy for long enough, start over
+	}
+	h.ejections++
+	ejection := d.config.BaseEjectionTime
+	for i := 1; i < h.ejections && ejection < d.config.MaxEjectionTime; i++ {
+		ejection *= 2
+	}
+	if ejection > d.config.MaxEjectionTime {
+		ejection = d.config.MaxEjectionTime
+	}
+
+	h.ejectedUntil = now.Add(ejection)
+	h.consecutive, h.requests, h.failures = 0, 0, 0
+	d.version++
+
+	d.logger.Log("instance", instance, "ejected", ejection, "ejections", h.ejections)
+	d.config.Ejections.Add(1)
+}
+
+// filter returns the endpoints of the instances that aren't ejected.
+func (d *outlierDetector) filter(instanceEndpoints []InstanceEndpoint) ([]endpoint.Endpoint, []InstanceEndpoint) {
+	now := d.timeNow()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if sameInstanceEndpoints(d.lastInput, instanceEndpoints) && d.lastVersion == d.version &&
+		(d.nextReturn.IsZero() || now.Before(d.nextReturn)) {
+		return d.endpoints, d.filtered
+	}
+
+	var (
+		endpoints  = make([]endpoint.Endpoint, 0, len(instanceEndpoints))
+		filtered   = make([]InstanceEndpoint, 0, len(instanceEndpoints))
+		nextReturn time.Time
+	)
+	for _, ie := range instanceEndpoints {
+		h, ok := d.hosts[ie.Instance]
+		if ok && h.down {
+			continue
+		}
+		if ok && now.Before(h.ejectedUntil) {
+			if nextReturn.IsZero() || h.ejectedUntil.Before(nextReturn) {
+				nextReturn = h.ejectedUntil
+			}
+			continue
+		}
+		endpoints = append(endpoints, ie.Endpoint)
+		filtered = append(filtered, ie)
+	}
+
+	d.lastInput, d.lastVersion, d.nextReturn = instanceEndpoints, d.version, nextReturn
+	d.endpoints, d.filtered = endpoints, filtered
+	return endpoints, filtered
+}
+
+// sameInstanceEndpoints reports whether a and b are the same slice.
+func sameInstanceEndpoints(a, b []InstanceEndpoint) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
@@ -0,0 +1,199 @@
+package sd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/go-kit/log"
+)
+
+func TestOutlierDetection(t *testing.T) {
+	var (
+		failing = map[string]bool{"a": true}
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return failer(failing, instance), nil, nil
+		}
+		ejections = generic.NewCounter("ejections")
+		cache     = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{
+			outlierDetection: true,
+			outlierConfig: OutlierConfig{
+				ConsecutiveFailures: 3,
+				BaseEjectionTime:    time.Minute,
+				MaxEjectionPercent:  50,
+				Ejections:           ejections,
+			},
+		})
+		now = time.Now()
+	)
+	cache.timeNow = func() time.Time { return now }
+	cache.Update(Event{Instances: []string{"a", "b"}})
+
+	instanceEndpoints, _ := cache.InstanceEndpoints()
+	a := instanceEndpoints[0]
+	for i := 0; i < 3; i++ {
+		a.Endpoint(context.Background(), struct{}{})
+	}
+	assertEndpointsLen(t, cache, 1)
+	if want, have := 1.0, ejections.Value(); want != have {
+		t.Errorf("want %v ejections, have %v", want, have)
+	}
+	if instanceEndpoints, _ := cache.InstanceEndpoints(); instanceEndpoints[0].Instance != "b" {
+		t.Errorf("want b, have %s", instanceEndpoints[0].Instance)
+	}
+
+	// Requests in flight while ejected don't count.
+	a.Endpoint(context.Background(), struct{}{})
+
+	// The instance comes back after the ejection time.
+	now = now.Add(time.Minute)
+	assertEndpointsLen(t, cache, 2)
+
+	// It keeps failing, so it's ejected for twice as long.
+	for i := 0; i < 3; i++ {
+		a.Endpoint(context.Background(), struct{}{})
+	}
+	assertEndpointsLen(t, cache, 1)
+	now = now.Add(time.Minute)
+	assertEndpointsLen(t, cache, 1)
+	now = now.Add(time.Minute)
+	assertEndpointsLen(t, cache, 2)
+}
+
+func TestOutlierDetectionMaxEjectionPercent(t *testing.T) {
+	var (
+		failing = map[string]bool{"a": true, "b": true}
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return failer(failing, instance), nil, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{
+			outlierDetection: true,
+			outlierConfig:    OutlierConfig{ConsecutiveFailures: 1, MaxEjectionPercent: 50},
+		})
+	)
+	cache.Update(Event{Instances: []string{"a", "b"}})
+
+	instanceEndpoints, _ := cache.InstanceEndpoints()
+	for _, ie := range instanceEndpoints {
+		ie.Endpoint(context.Background(), struct{}{})
+	}
+	assertEndpointsLen(t, cache, 1)
+}
+
+func TestOutlierDetectionDefaults(t *testing.T) {
+	var (
+		failing = map[string]bool{"a": true, "b": true}
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return failer(failing, instance), nil, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{
+			outlierDetection: true,
+			outlierConfig:    OutlierConfig{},
+		})
+	)
+	cache.Update(Event{Instances: []string{"a", "b", "c"}})
+
+	// With 3 instances, 10% allows no ejection, but one is always allowed.
+	instanceEndpoints, _ := cache.InstanceEndpoints()
+	for i := 0; i < 5; i++ {
+		for _, ie := range instanceEndpoints {
+			ie.Endpoint(context.Background(), struct{}{})
+		}
+	}
+	assertEndpointsLen(t, cache, 2)
+}
+
+func TestOutlierDetectionFailureRate(t *testing.T) {
+	var (
+		calls   int
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(context.Context, interface{}) (interface{}, error) {
+				if calls++; calls%2 == 0 {
+					return nil, errors.New("fail")
+				}
+				return struct{}{}, nil
+			}, nil, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{
+			outlierDetection: true,
+			outlierConfig:    OutlierConfig{FailureRate: 0.5, MinRequests: 10, MaxEjectionPercent: 100},
+		})
+	)
+	cache.Update(Event{Instances: []string{"a"}})
+
+	endpoints, _ := cache.Endpoints()
+	for i := 0; i < 9; i++ {
+		endpoints[0](context.Background(), struct{}{})
+	}
+	assertEndpointsLen(t, cache, 1)
+	endpoints[0](context.Background(), struct{}{})
+	assertEndpointsLen(t, cache, 0)
+}
+
+func TestOutlierDetectionIgnoresCanceledRequests(t *testing.T) {
+	var (
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			return func(ctx context.Context, _ interface{}) (interface{}, error) { return nil, ctx.Err() }, nil, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{
+			outlierDetection: true,
+			outlierConfig:    OutlierConfig{ConsecutiveFailures: 1, MaxEjectionPercent: 100},
+		})
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	cancel()
+	cache.Update(Event{Instances: []string{"a"}})
+
+	endpoints, _ := cache.Endpoints()
+	endpoints[0](ctx, struct{}{})
+	assertEndpointsLen(t, cache, 1)
+}
+
+func TestOutlierDetectionHealthWatcher(t *testing.T) {
+	var (
+		watchers = map[string]*healthWatcher{}
+		factory  = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			w := &healthWatcher{}
+			watchers[instance] = w
+			return failer(nil, instance), w, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{outlierDetection: true})
+	)
+	cache.Update(Event{Instances: []string{"a", "b"}})
+	assertEndpointsLen(t, cache, 2)
+
+	// Unhealthy instances aren't subject to MaxEjectionPercent.
+	watchers["a"].report(false)
+	watchers["b"].report(false)
+	assertEndpointsLen(t, cache, 0)
+
+	watchers["a"].report(true)
+	assertEndpointsLen(t, cache, 1)
+
+	// Reports for instances that went away are ignored.
+	stale := watchers["b"]
+	cache.Update(Event{Instances: []string{"a"}})
+	cache.Update(Event{Instances: []string{"a", "b"}})
+	stale.report(false)
+	assertEndpointsLen(t, cache, 2)
+}
+
+type healthWatcher struct {
+	report func(healthy bool)
+}
+
+func (w *healthWatcher) WatchHealth(report func(healthy bool)) { w.report = report }
+func (w *healthWatcher) Close() error                          { return nil }
+
+func failer(failing map[string]bool, instance string) endpoint.Endpoint {
+	return func(context.Context, interface{}) (interface{}, error) {
+		if failing[instance] {
+			return nil, errors.New(instance + " failed")
+		}
+		return struct{}{}, nil
+	}
+}
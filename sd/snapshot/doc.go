@@ -0,0 +1,5 @@
+// Package snapshot provides an Instancer decorator that persists the last
+// good set of instances yielded by another Instancer to a file, and keeps
+// serving it while the discovery backend is unreachable, including when the
+// process starts during an outage.
+package snapshot
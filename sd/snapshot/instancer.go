@@ -0,0 +1,154 @@
+package snapshot
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+// Instancer decorates another Instancer. It saves every non-empty set of
+// instances yielded by the decorated Instancer to a snapshot file, and keeps
+// publishing the last set when the decorated Instancer fails, rather than
+// passing the error through. Until the decorated Instancer yields its first
+// set, the Instancer publishes the instances of the snapshot file, if any.
+//
+// Instances that aren't fresh from the decorated Instancer are flagged as
+// stale in the State of the Instancer. Errors are only passed through when
+// there are no instances to fall back on.
+type Instancer struct {
+	cache  *instance.Cache
+	src    sd.Instancer
+	path   string
+	logger log.Logger
+	events chan sd.Event
+	quitc  chan struct{}
+
+	mtx   sync.RWMutex
+	state State
+	last  snapshot // published instances, owned by the loop goroutine
+	saved snapshot // owned by the loop goroutine
+}
+
+// State describes the freshness of the instances published by an Instancer.
+type State struct {
+	// Stale is true if the instances come from the snapshot file, or if the
+	// decorated Instancer failed since it yielded them.
+	Stale bool
+
+	// Err is the last error of the decorated Instancer, if it failed since
+	// it last yielded instances.
+	Err error
+
+	// Updated is when the decorated Instancer yielded the instances.
+	Updated time.Time
+}
+
+// NewInstancer returns an Instancer that publishes the instances of src, and
+// persists them to the snapshot file at path. The snapshot file is loaded
+// immediately, if it exists. Stopping the returned Instancer doesn't stop src.
+func NewInstancer(src sd.Instancer, path string, logger log.Logger) *Instancer {
+	in := &Instancer{
+		cache:  instance.NewCache(),
+		src:    src,
+		path:   path,
+		logger: log.With(logger, "snapshot", path),
+		events: make(chan sd.Event),
+		quitc:  make(chan struct{}),
+	}
+
+	s, err := load(path)
+	switch {
+	case err == nil && len(s.Instances) > 0:
+		in.logger.Log("action", "load", "instances", len(s.Instances), "updated", s.Updated)
+		in.last, in.saved = s, s
+		in.state = State{Stale: true, Updated: s.Updated}
+		in.cache.Update(sd.Event{Instances: s.Instances, Records: s.Records})
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		in.logger.Log("during", "load", "err", err)
+	}
+
+	go in.loop()
+	src.Register(in.events)
+	return in
+}
+
+// State returns the freshness of the published instances.
+func (in *Instancer) State() State {
+	in.mtx.RLock()
+	defer in.mtx.RUnlock()
+	return in.state
+}
+
+// Stop terminates the Instancer. It doesn't stop the decorated Instancer.
+func (in *Instancer) Stop() {
+	in.src.Deregister(in.events)
+	close(in.quitc)
+}
+
+// Register implements Instancer.
+func (in *Instancer) Register(ch chan<- sd.Event) {
+	in.cache.Register(ch)
+}
+
+// Deregister implements Instancer.
+func (in *Instancer) Deregister(ch chan<- sd.Event) {
+	in.cache.Deregister(ch)
+}
+
+func (in *Instancer) loop() {
+	for {
+		select {
+		case event := <-in.events:
+			in.handle(event)
+		case <-in.quitc:
+			return
+		}
+	}
+}
+
+func (in *Instancer) handle(event sd.Event) {
+	if event.Err != nil {
+		if len(in.last.Instances) == 0 {
+			in.setState(State{Err: event.Err})
+			in.cache.Update(event)
+			return
+		}
+		in.logger.Log("err", event.Err, "stale_instances", len(in.last.Instances))
+		in.setState(State{Stale: true, Err: event.Err, Updated: in.last.Updated})
+		in.cache.Update(sd.Event{Instances: in.last.Instances, Records: in.last.Records})
+		return
+	}
+
+	in.last = snapshot{Updated: time.Now(), Instances: event.Instances, Records: event.Records}
+	in.persist()
+	in.setState(State{Updated: in.last.Updated})
+	in.cache.Update(event)
+}
+
+// persist saves the last instances to the snapshot file, if they changed.
+// Empty sets aren't worth falling back on, so they aren't saved.
+func (in *Instancer) persist() {
+	if len(in.last.Instances) == 0 {
+		return
+	}
+	if reflect.DeepEqual(in.saved.Instances, in.last.Instances) && reflect.DeepEqual(in.saved.Records, in.last.Records) {
+		return
+	}
+	if err := save(in.path, in.last); err != nil {
+		in.logger.Log("during", "save", "err", err)
+		return
+	}
+	in.saved = in.last
+}
+
+func (in *Instancer) setState(s State) {
+	in.mtx.Lock()
+	defer in.mtx.Unlock()
+	in.state = s
+}
@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+	"github.com/go-kit/kit/sd/internal/instance"
+	"github.com/go-kit/log"
+)
+
+var _ sd.Instancer = (*Instancer)(nil) // API check
+
+func TestInstancer(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "instances.json")
+		src   = instance.NewCache()
+		myErr = errors.New("unreachable")
+	)
+	src.Update(sd.Event{
+		Instances: []string{"1.0.0.1:80", "1.0.0.2:80"},
+		Records:   []sd.InstanceRecord{{Instance: "1.0.0.1:80", Zone: "a"}, {Instance: "1.0.0.2:80", Zone: "b"}},
+	})
+
+	in := NewInstancer(src, path, log.NewNopLogger())
+	defer in.Stop()
+	waitInstances(t, in, "1.0.0.1:80", "1.0.0.2:80")
+	if state := in.State(); state.Stale || state.Err != nil || state.Updated.IsZero() {
+		t.Errorf("want fresh state, have %+v", state)
+	}
+
+	saved, err := load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "b", saved.Records[1].Zone; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// Errors don't make the instances go away.
+	src.Update(sd.Event{Err: myErr})
+	waitState(t, in, func(s State) bool { return s.Stale })
+	if want, have := myErr, in.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"1.0.0.1:80", "1.0.0.2:80"}, in.cache.State().Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Empty sets are published, but not saved.
+	src.Update(sd.Event{Instances: []string{}})
+	waitInstances(t, in)
+	if saved, err = load(path); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"1.0.0.1:80", "1.0.0.2:80"}, saved.Instances; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestInstancerColdStart(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "instances.json")
+		src   = instance.NewCache()
+		myErr = errors.New("unreachable")
+		then  = time.Now().Add(-time.Hour)
+	)
+	if err := save(path, snapshot{Updated: then, Instances: []string{"1.0.0.1:80"}}); err != nil {
+		t.Fatal(err)
+	}
+	src.Update(sd.Event{Err: myErr})
+
+	in := NewInstancer(src, path, log.NewNopLogger())
+	defer in.Stop()
+	waitState(t, in, func(s State) bool { return s.Err != nil })
+	if state := in.State(); !state.Stale || state.Err != myErr || !state.Updated.Equal(then) {
+		t.Errorf("want stale state updated at %v, have %+v", then, state)
+	}
+	waitInstances(t, in, "1.0.0.1:80")
+
+	// The backend comes back.
+	src.Update(sd.Event{Instances: []string{"1.0.0.2:80"}})
+	waitInstances(t, in, "1.0.0.2:80")
+	if in.State().Stale {
+		t.Error("instances are still stale")
+	}
+}
+
+func TestInstancerNoSnapshot(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "instances.json")
+		src   = instance.NewCache()
+		myErr = errors.New("unreachable")
+	)
+	src.Update(sd.Event{Err: myErr})
+
+	in := NewInstancer(src, path, log.NewNopLogger())
+	defer in.Stop()
+	waitState(t, in, func(s State) bool { return s.Err != nil })
+	if want, have := myErr, in.cache.State().Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("want no snapshot, have %v", err)
+	}
+}
+
+func waitInstances(t *testing.T, in *Instancer, instances ...string) {
+	t.Helper()
+	if len(instances) == 0 {
+		instances = []string{}
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if have := in.cache.State().Instances; reflect.DeepEqual(instances, have) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("want %v, have %v", instances, in.cache.State().Instances)
+		}
+	}
+}
+
+func waitState(t *testing.T, in *Instancer, ok func(State) bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !ok(in.State()); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected state %+v", in.State())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/kit/sd"
+)
+
+// snapshot is the file format of a snapshot.
+type snapshot struct {
+	Updated   time.Time           `json:"updated"`
+	Instances []string            `json:"instances"`
+	Records   []sd.InstanceRecord `json:"records,omitempty"`
+}
+
+func load(path string) (snapshot, error) {
+	var s snapshot
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(buf, &s)
+	return s, err
+}
+
+// save writes the snapshot to a temporary file, and renames it to path, so
+// that readers never see a partially written snapshot.
+func save(path string, s snapshot) error {
+	buf, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after a successful rename
+
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,73 @@
+package sd
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// Subset returns an EndpointerOption that limits the Endpointer to a
+// deterministic subset of at most size instances, so that clients of large
+// fleets don't connect to every instance. The factory is only invoked for the
+// instances in the subset.
+//
+// Subsets are chosen with the deterministic subsetting algorithm described in
+// the Site Reliability Engineering book: clients are grouped in rounds of
+// len(instances)/size clients, and the clients of a round get disjoint subsets
+// of a shuffle of the instances specific to the round. Numbering the clients
+// of a service consecutively from zero, e.g. with the ordinal of a pod in a
+// StatefulSet, spreads them evenly across the instances.
+//
+// Instances are shuffled by sorting them by hash, so adding or removing an
+// instance only replaces a single instance of most subsets, unless it changes
+// the number of clients per round.
+func Subset(clientID, size int) EndpointerOption {
+	return func(opts *endpointerOptions) {
+		opts.subsetClientID = clientID
+		opts.subsetSize = size
+	}
+}
+
+// subset returns the subset of the instances for the client, sorted.
+func subset(instances []string, clientID, size int) []string {
+	if size <= 0 || len(instances) <= size {
+		return instances
+	}
+
+	var (
+		count = uint64(len(instances) / size) // clients per round
+		round = uint64(clientID) / count
+		id    = uint64(clientID) % count
+	)
+
+	type shuffled struct {
+		instance string
+		hash     uint64
+	}
+	s := make([]shuffled, len(instances))
+	for i, instance := range instances {
+		s[i] = shuffled{instance, roundHash(round, instance)}
+	}
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].hash != s[j].hash {
+			return s[i].hash < s[j].hash
+		}
+		return s[i].instance < s[j].instance
+	})
+
+	result := make([]string, size)
+	for i := range result {
+		result[i] = s[int(id)*size+i].instance
+	}
+	sort.Strings(result)
+	return result
+}
+
+func roundHash(round uint64, instance string) uint64 {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], round)
+	h := fnv.New64a()
+	h.Write(b[:])
+	h.Write([]byte(instance))
+	return h.Sum64()
+}
@@ -0,0 +1,82 @@
+package sd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/log"
+)
+
+func TestSubset(t *testing.T) {
+	instances := make([]string, 12)
+	for i := range instances {
+		instances[i] = fmt.Sprintf("10.0.0.%d:80", i)
+	}
+
+	// Every round of clients spreads evenly across all instances.
+	for round := 0; round < 3; round++ {
+		connections := map[string]int{}
+		for clientID := round * 4; clientID < round*4+4; clientID++ {
+			s := subset(instances, clientID, 3)
+			if want, have := 3, len(s); want != have {
+				t.Fatalf("want %d, have %d", want, have)
+			}
+			for _, instance := range s {
+				connections[instance]++
+			}
+		}
+		for _, instance := range instances {
+			if want, have := 1, connections[instance]; want != have {
+				t.Errorf("round %d: %s: want %d connections, have %d", round, instance, want, have)
+			}
+		}
+	}
+
+	// Adding an instance barely changes the subsets.
+	more := append(instances[:len(instances):len(instances)], "10.0.0.99:80")
+	for clientID := 0; clientID < 12; clientID++ {
+		before := map[string]bool{}
+		for _, instance := range subset(instances, clientID, 3) {
+			before[instance] = true
+		}
+		changed := 0
+		for _, instance := range subset(more, clientID, 3) {
+			if !before[instance] {
+				changed++
+			}
+		}
+		if changed > 1 {
+			t.Errorf("client %d: %d instances changed", clientID, changed)
+		}
+	}
+
+	if want, have := 2, len(subset(instances[:2], 0, 3)); want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
+
+func TestSubsetEndpointer(t *testing.T) {
+	var (
+		created = map[string]bool{}
+		factory = func(instance string) (endpoint.Endpoint, io.Closer, error) {
+			created[instance] = true
+			return func(context.Context, interface{}) (interface{}, error) { return instance, nil }, nil, nil
+		}
+		cache = newEndpointCache(factory, log.NewNopLogger(), endpointerOptions{subsetClientID: 7, subsetSize: 2})
+	)
+	cache.Update(Event{Instances: []string{"a", "b", "c", "d", "e", "f"}})
+
+	instanceEndpoints, _ := cache.InstanceEndpoints()
+	if want, have := 2, len(instanceEndpoints); want != have {
+		t.Fatalf("want %d, have %d", want, have)
+	}
+	if want, have := 2, len(created); want != have {
+		t.Errorf("want %d instances created, have %d", want, have)
+	}
+	if instanceEndpoints[0].Instance > instanceEndpoints[1].Instance {
+		t.Errorf("endpoints aren't sorted: %s, %s", instanceEndpoints[0].Instance, instanceEndpoints[1].Instance)
+	}
+}
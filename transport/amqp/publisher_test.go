@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
 	"time"
 
 	amqptransport "github.com/go-kit/kit/transport/amqp"
+	"github.com/go-kit/kit/transport/baggage"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -268,3 +270,27 @@ func TestSendAndForgetPublisher(t *testing.T) {
 	}
 
 }
+
+func TestBaggage(t *testing.T) {
+	var (
+		ctx = baggage.Set(baggage.Set(context.Background(), "X-Tenant-ID", "acme"), "X-Secret", "hunter2")
+		pub amqp.Publishing
+	)
+	amqptransport.InjectBaggage("X-Tenant-ID")(ctx, &pub, nil)
+	if want, have := (amqp.Table{"X-Tenant-ID": "acme"}), pub.Headers; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	deliv := &amqp.Delivery{Headers: amqp.Table{"x-tenant-id": "acme", "X-Secret": "hunter2"}}
+	extracted := amqptransport.ExtractBaggage("X-Tenant-ID")(context.Background(), &amqp.Publishing{}, deliv)
+	if want, have := map[string]string{"x-tenant-id": "acme"}, baggage.FromContext(extracted); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	var reply amqp.Publishing
+	amqptransport.InjectResponseBaggage("X-Tenant-ID")(extracted, deliv, nil, &reply)
+	extracted = amqptransport.ExtractResponseBaggage("X-Tenant-ID")(context.Background(), &amqp.Delivery{Headers: reply.Headers})
+	if value, _ := baggage.Get(extracted, "X-Tenant-ID"); value != "acme" {
+		t.Errorf("want acme, have %q", value)
+	}
+}
@@ -2,9 +2,12 @@ package amqp
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/go-kit/kit/transport/baggage"
 )
 
 // RequestFunc may take information from a publisher request and put it into a
@@ -124,6 +127,65 @@ func SetAckAfterEndpoint(multiple bool) SubscriberResponseFunc {
 	}
 }
 
+// ExtractBaggage returns a RequestFunc that copies the given headers of the
+// Delivery, if present, into the baggage of the context. It's meant for
+// Subscribers. Header names are matched case-insensitively.
+func ExtractBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, _ *amqp.Publishing, deliv *amqp.Delivery) context.Context {
+		if deliv == nil {
+			return ctx
+		}
+		return baggage.Extract(ctx, keys, tableValue(deliv.Headers))
+	}
+}
+
+// InjectBaggage returns a RequestFunc that sets the given keys of the baggage
+// of the context, if present, as headers of the Publishing. It's meant for
+// Publishers.
+func InjectBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, pub *amqp.Publishing, _ *amqp.Delivery) context.Context {
+		injectTable(ctx, keys, pub)
+		return ctx
+	}
+}
+
+// InjectResponseBaggage returns a SubscriberResponseFunc that sets the given
+// keys of the baggage of the context, if present, as headers of the reply.
+func InjectResponseBaggage(keys ...string) SubscriberResponseFunc {
+	return func(ctx context.Context, _ *amqp.Delivery, _ Channel, pub *amqp.Publishing) context.Context {
+		injectTable(ctx, keys, pub)
+		return ctx
+	}
+}
+
+// ExtractResponseBaggage returns a PublisherResponseFunc that copies the given
+// headers of the reply, if present, into the baggage of the context.
+func ExtractResponseBaggage(keys ...string) PublisherResponseFunc {
+	return func(ctx context.Context, deliv *amqp.Delivery) context.Context {
+		return baggage.Extract(ctx, keys, tableValue(deliv.Headers))
+	}
+}
+
+func tableValue(t amqp.Table) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		for k, v := range t {
+			if s, ok := v.(string); ok && strings.EqualFold(k, key) {
+				return s, true
+			}
+		}
+		return "", false
+	}
+}
+
+func injectTable(ctx context.Context, keys []string, pub *amqp.Publishing) {
+	baggage.Inject(ctx, keys, func(key, value string) {
+		if pub.Headers == nil {
+			pub.Headers = amqp.Table{}
+		}
+		pub.Headers[key] = value
+	})
+}
+
 func getPublishExchange(ctx context.Context) string {
 	if exchange := ctx.Value(ContextKeyExchange); exchange != nil {
 		return exchange.(string)
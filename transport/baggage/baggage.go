@@ -0,0 +1,60 @@
+package baggage
+
+import (
+	"context"
+	"strings"
+)
+
+type contextKey struct{}
+
+// Set returns a copy of the context carrying the key-value pair, in addition
+// to the baggage already in it.
+func Set(ctx context.Context, key, value string) context.Context {
+	prev, _ := ctx.Value(contextKey{}).(map[string]string)
+	next := make(map[string]string, len(prev)+1)
+	for k, v := range prev {
+		next[k] = v
+	}
+	next[strings.ToLower(key)] = value
+	return context.WithValue(ctx, contextKey{}, next)
+}
+
+// Get returns the value of the key in the baggage of the context, if any.
+func Get(ctx context.Context, key string) (string, bool) {
+	m, _ := ctx.Value(contextKey{}).(map[string]string)
+	value, ok := m[strings.ToLower(key)]
+	return value, ok
+}
+
+// FromContext returns a copy of the baggage of the context, with lowercase
+// keys.
+func FromContext(ctx context.Context) map[string]string {
+	m, _ := ctx.Value(contextKey{}).(map[string]string)
+	baggage := make(map[string]string, len(m))
+	for k, v := range m {
+		baggage[k] = v
+	}
+	return baggage
+}
+
+// Extract copies the allowed keys into the baggage of the context, looking
+// their values up with get. Transports use it to implement their
+// ExtractBaggage funcs.
+func Extract(ctx context.Context, keys []string, get func(key string) (string, bool)) context.Context {
+	for _, key := range keys {
+		if value, ok := get(key); ok {
+			ctx = Set(ctx, key, value)
+		}
+	}
+	return ctx
+}
+
+// Inject calls set for each of the allowed keys in the baggage of the
+// context. Transports use it to implement their InjectBaggage funcs.
+func Inject(ctx context.Context, keys []string, set func(key, value string)) {
+	for _, key := range keys {
+		if value, ok := Get(ctx, key); ok {
+			set(key, value)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package baggage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestBaggage(t *testing.T) {
+	ctx := Set(context.Background(), "X-Tenant-ID", "acme")
+	child := Set(ctx, "x-request-id", "42")
+
+	if value, ok := Get(child, "x-tenant-id"); !ok || value != "acme" {
+		t.Errorf("want acme, have %q", value)
+	}
+	if _, ok := Get(ctx, "X-Request-ID"); ok {
+		t.Error("parent context sees the baggage of its child")
+	}
+	if want, have := map[string]string{"x-tenant-id": "acme", "x-request-id": "42"}, FromContext(child); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	var (
+		incoming = map[string]string{"X-Tenant-ID": "acme", "Authorization": "secret"}
+		outgoing = map[string]string{}
+		keys     = []string{"X-Tenant-ID", "Authorization-Not", "X-Request-ID"}
+	)
+	ctx := Extract(context.Background(), keys, func(key string) (string, bool) {
+		value, ok := incoming[key]
+		return value, ok
+	})
+	Inject(ctx, keys, func(key, value string) { outgoing[key] = value })
+
+	if want, have := map[string]string{"X-Tenant-ID": "acme"}, outgoing; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,11 @@
+// Package baggage carries request-scoped key-value pairs, such as a tenant or
+// request ID, from inbound to outbound requests, independent of the
+// transports involved.
+//
+// Servers extract the allowed keys of incoming requests into the context with
+// the ExtractBaggage request funcs of their transport package, and clients
+// inject them into outgoing requests with InjectBaggage. Only the keys passed
+// to those funcs are propagated, so callers can't smuggle arbitrary headers
+// through a chain of services. Keys are case-insensitive, as HTTP headers and
+// gRPC metadata are.
+package baggage
@@ -7,6 +7,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/go-kit/kit/endpoint"
 )
@@ -23,6 +24,7 @@ type Client struct {
 	before      []ClientRequestFunc
 	after       []ClientResponseFunc
 	finalizer   []ClientFinalizerFunc
+	errDecoder  ErrorDecoder
 }
 
 // NewClient constructs a usable Client for a single remote endpoint.
@@ -82,6 +84,13 @@ func ClientFinalizer(f ...ClientFinalizerFunc) ClientOption {
 	return func(s *Client) { s.finalizer = append(s.finalizer, f...) }
 }
 
+// ClientErrorDecoder is used to convert the errors returned by the server,
+// which carry a gRPC status, back to domain errors. By default, the errors are
+// returned as is, and status.FromError can be used to inspect them.
+func ClientErrorDecoder(d ErrorDecoder) ClientOption {
+	return func(c *Client) { c.errDecoder = d }
+}
+
 // Endpoint returns a usable endpoint that will invoke the gRPC specified by the
 // client.
 func (c Client) Endpoint() endpoint.Endpoint {
@@ -116,7 +125,7 @@ func (c Client) Endpoint() endpoint.Endpoint {
 			ctx, c.method, req, grpcReply, grpc.Header(&header),
 			grpc.Trailer(&trailer),
 		); err != nil {
-			return nil, err
+			return nil, c.decodeError(ctx, err)
 		}
 
 		for _, f := range c.after {
@@ -131,6 +140,25 @@ func (c Client) Endpoint() endpoint.Endpoint {
 	}
 }
 
+// decodeError converts errors carrying a gRPC status with the ErrorDecoder,
+// if any.
+func (c Client) decodeError(ctx context.Context, err error) error {
+	if c.errDecoder == nil {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	return c.errDecoder(ctx, st)
+}
+
+// ErrorDecoder is responsible for converting the gRPC status of a failed call
+// back to an error. Users are encouraged to use custom ErrorDecoders to
+// restore their own error types, e.g. from the code and details of the
+// status.
+type ErrorDecoder func(ctx context.Context, st *status.Status) error
+
 // ClientFinalizerFunc can be used to perform work at the end of a client gRPC
 // request, after the response is returned. The principal
 // intended use is for error logging. Additional response parameters are
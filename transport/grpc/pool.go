@@ -0,0 +1,216 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/sd"
+)
+
+// ErrConnClosed is returned by endpoints whose connection was closed by sd,
+// e.g. by a balancer still holding them after their instance went away.
+var ErrConnClosed = errors.New("grpc: connection closed")
+
+// ConnPool shares gRPC connections between the endpoints created for the same
+// instance, e.g. by factories for different methods of a service. It dials
+// an instance on the first request to one of its endpoints, and closes the
+// connection once all of them are closed.
+type ConnPool struct {
+	options []grpc.DialOption
+
+	mtx   sync.Mutex
+	conns map[string]*pooledConn
+}
+
+// NewConnPool returns a ConnPool dialing instances with the given options.
+// They must include transport credentials, e.g.
+// grpc.WithTransportCredentials(insecure.NewCredentials()).
+func NewConnPool(options ...grpc.DialOption) *ConnPool {
+	return &ConnPool{
+		options: options,
+		conns:   map[string]*pooledConn{},
+	}
+}
+
+// Factory returns an sd.Factory yielding Client endpoints for the method,
+// over the pooled connection to each instance. The arguments are those of
+// NewClient.
+//
+// The io.Closer returned by the factory releases the connection. It also
+// implements sd.HealthWatcher, reporting the instance unhealthy while its
+// connection is in the TRANSIENT_FAILURE state, so Endpointers with
+// sd.OutlierDetection stop using it until it reconnects.
+func (p *ConnPool) Factory(
+	serviceName string,
+	method string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	grpcReply interface{},
+	options ...ClientOption,
+) sd.Factory {
+	return func(instance string) (endpoint.Endpoint, io.Closer, error) {
+		h := &connHandle{conn: p.acquire(instance)}
+		h.newClient = func(cc *grpc.ClientConn) endpoint.Endpoint {
+			return NewClient(cc, serviceName, method, enc, dec, grpcReply, options...).Endpoint()
+		}
+		return h.endpoint, h, nil
+	}
+}
+
+func (p *ConnPool) acquire(instance string) *pooledConn {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	c, ok := p.conns[instance]
+	if !ok {
+		c = &pooledConn{
+			pool:     p,
+			instance: instance,
+			healthy:  true,
+			watchers: map[*connHandle]func(bool){},
+		}
+		p.conns[instance] = c
+	}
+	c.refs++
+	return c
+}
+
+func (p *ConnPool) release(c *pooledConn) error {
+	p.mtx.Lock()
+	c.refs--
+	last := c.refs == 0
+	if last {
+		delete(p.conns, c.instance)
+	}
+	p.mtx.Unlock()
+
+	if !last {
+		return nil
+	}
+	return c.close()
+}
+
+// pooledConn is the connection to an instance, dialed on first use.
+type pooledConn struct {
+	pool     *ConnPool
+	instance string
+	refs     int // guarded by the pool's mutex
+
+	mtx      sync.Mutex
+	cc       *grpc.ClientConn // nil until dialed
+	cancel   context.CancelFunc
+	closed   bool
+	healthy  bool
+	watchers map[*connHandle]func(bool)
+}
+
+func (c *pooledConn) dial() (*grpc.ClientConn, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.closed {
+		return nil, ErrConnClosed
+	}
+	if c.cc != nil {
+		return c.cc, nil
+	}
+	cc, err := grpc.Dial(c.instance, c.pool.options...)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cc, c.cancel = cc, cancel
+	go c.watch(ctx, cc)
+	return cc, nil
+}
+
+// watch reports the state changes of the connection until it's closed.
+func (c *pooledConn) watch(ctx context.Context, cc *grpc.ClientConn) {
+	for state := cc.GetState(); ; state = cc.GetState() {
+		c.setHealthy(state != connectivity.TransientFailure)
+		if !cc.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+func (c *pooledConn) setHealthy(healthy bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.closed || c.healthy == healthy {
+		return
+	}
+	c.healthy = healthy
+	for _, report := range c.watchers {
+		report(healthy)
+	}
+}
+
+func (c *pooledConn) addWatcher(h *connHandle, report func(bool)) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.watchers[h] = report
+	if !c.healthy {
+		report(false)
+	}
+}
+
+func (c *pooledConn) removeWatcher(h *connHandle) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.watchers, h)
+}
+
+func (c *pooledConn) close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.closed = true
+	if c.cc == nil {
+		return nil
+	}
+	c.cancel()
+	return c.cc.Close()
+}
+
+// connHandle is a reference to a pooledConn, held by an endpoint.
+type connHandle struct {
+	conn      *pooledConn
+	newClient func(*grpc.ClientConn) endpoint.Endpoint
+
+	once      sync.Once
+	e         endpoint.Endpoint
+	closeOnce sync.Once
+}
+
+func (h *connHandle) endpoint(ctx context.Context, request interface{}) (interface{}, error) {
+	cc, err := h.conn.dial()
+	if err != nil {
+		return nil, err
+	}
+	h.once.Do(func() { h.e = h.newClient(cc) })
+	return h.e(ctx, request)
+}
+
+// WatchHealth implements sd.HealthWatcher.
+func (h *connHandle) WatchHealth(report func(healthy bool)) {
+	h.conn.addWatcher(h, report)
+}
+
+// Close implements io.Closer.
+func (h *connHandle) Close() error {
+	var err error
+	h.closeOnce.Do(func() {
+		h.conn.removeWatcher(h)
+		err = h.conn.pool.release(h.conn)
+	})
+	return err
+}
@@ -0,0 +1,118 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kit/kit/sd"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/log"
+)
+
+func TestConnPool(t *testing.T) {
+	var (
+		lis    = bufconn.Listen(1 << 20)
+		server = grpc.NewServer()
+
+		mtx   sync.Mutex
+		dials = map[string]int{}
+		pool  = kitgrpc.NewConnPool(
+			grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+				mtx.Lock()
+				dials[addr]++
+				mtx.Unlock()
+				return lis.Dial()
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		factory = pool.Factory("kit.test.Unary", "Unary", encodeString, decodeString, wrapperspb.StringValue{})
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "kit.test.Unary",
+		HandlerType: (*kitgrpc.Handler)(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Unary", Handler: unaryHandler}},
+	}, kitgrpc.NewServer(endpointEcho, decodeString, encodeString))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	dialed := func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return dials["a"]
+	}
+
+	e1, c1, _ := factory("a")
+	e2, c2, _ := factory("a")
+	if want, have := 0, dialed(); want != have {
+		t.Fatalf("want %d dials before the first request, have %d", want, have)
+	}
+	for _, e := range []func(context.Context, interface{}) (interface{}, error){e1, e2} {
+		if response, err := e(context.Background(), "hi"); err != nil || response != "hi" {
+			t.Fatalf("want hi, have %v (%v)", response, err)
+		}
+	}
+	if want, have := 1, dialed(); want != have {
+		t.Errorf("want %d dial, have %d", want, have)
+	}
+
+	// The connection stays up until its last endpoint is closed.
+	c1.Close()
+	if _, err := e2(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	c2.Close()
+	if _, err := e2(context.Background(), "hi"); !errors.Is(err, kitgrpc.ErrConnClosed) {
+		t.Errorf("want %v, have %v", kitgrpc.ErrConnClosed, err)
+	}
+
+	// New endpoints for the instance get a new connection.
+	e3, c3, _ := factory("a")
+	defer c3.Close()
+	if _, err := e3(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, dialed(); want != have {
+		t.Errorf("want %d dials, have %d", want, have)
+	}
+}
+
+func TestConnPoolHealth(t *testing.T) {
+	var (
+		pool = kitgrpc.NewConnPool(
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return nil, errors.New("connection refused")
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		factory    = pool.Factory("kit.test.Unary", "Unary", encodeString, decodeString, wrapperspb.StringValue{})
+		endpointer = sd.NewEndpointer(sd.FixedInstancer{"a"}, factory, log.NewNopLogger(), sd.OutlierDetection(sd.OutlierConfig{}))
+	)
+	defer endpointer.Close()
+
+	endpoints, err := endpointer.Endpoints()
+	if err != nil || len(endpoints) != 1 {
+		t.Fatalf("want 1 endpoint, have %d (%v)", len(endpoints), err)
+	}
+	if _, err := endpoints[0](context.Background(), "hi"); err == nil {
+		t.Fatal("want error, have none")
+	}
+
+	// The connection fails, so the instance is reported unhealthy.
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if endpoints, _ := endpointer.Endpoints(); len(endpoints) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("instance wasn't reported unhealthy")
+		}
+	}
+}
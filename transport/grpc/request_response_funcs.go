@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"google.golang.org/grpc/metadata"
+
+	"github.com/go-kit/kit/transport/baggage"
 )
 
 const (
@@ -65,6 +67,49 @@ func SetResponseTrailer(key, val string) ServerResponseFunc {
 	}
 }
 
+// ExtractBaggage returns a ServerRequestFunc that copies the given keys of the
+// request metadata, if present, into the baggage of the context.
+func ExtractBaggage(keys ...string) ServerRequestFunc {
+	return func(ctx context.Context, md metadata.MD) context.Context {
+		return baggage.Extract(ctx, keys, metadataValue(md))
+	}
+}
+
+// InjectBaggage returns a ClientRequestFunc that sets the given keys of the
+// baggage of the context, if present, in the request metadata.
+func InjectBaggage(keys ...string) ClientRequestFunc {
+	return func(ctx context.Context, md *metadata.MD) context.Context {
+		baggage.Inject(ctx, keys, func(key, value string) { md.Set(key, value) })
+		return ctx
+	}
+}
+
+// InjectResponseBaggage returns a ServerResponseFunc that sets the given keys
+// of the baggage of the context, if present, in the response header.
+func InjectResponseBaggage(keys ...string) ServerResponseFunc {
+	return func(ctx context.Context, header *metadata.MD, _ *metadata.MD) context.Context {
+		baggage.Inject(ctx, keys, func(key, value string) { header.Set(key, value) })
+		return ctx
+	}
+}
+
+// ExtractResponseBaggage returns a ClientResponseFunc that copies the given
+// keys of the response header, if present, into the baggage of the context.
+func ExtractResponseBaggage(keys ...string) ClientResponseFunc {
+	return func(ctx context.Context, header metadata.MD, _ metadata.MD) context.Context {
+		return baggage.Extract(ctx, keys, metadataValue(header))
+	}
+}
+
+func metadataValue(md metadata.MD) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0], true
+		}
+		return "", false
+	}
+}
+
 // EncodeKeyValue sanitizes a key-value pair for use in gRPC metadata headers.
 func EncodeKeyValue(key, val string) (string, string) {
 	key = strings.ToLower(key)
@@ -2,9 +2,12 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/go-kit/kit/endpoint"
 	"github.com/go-kit/kit/transport"
@@ -26,6 +29,7 @@ type Server struct {
 	before       []ServerRequestFunc
 	after        []ServerResponseFunc
 	finalizer    []ServerFinalizerFunc
+	errorEncoder ErrorEncoder
 	errorHandler transport.ErrorHandler
 }
 
@@ -44,6 +48,7 @@ func NewServer(
 		e:            e,
 		dec:          dec,
 		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
 		errorHandler: transport.NewLogErrorHandler(log.NewNopLogger()),
 	}
 	for _, option := range options {
@@ -67,6 +72,13 @@ func ServerAfter(after ...ServerResponseFunc) ServerOption {
 	return func(s *Server) { s.after = append(s.after, after...) }
 }
 
+// ServerErrorEncoder is used to convert errors to the gRPC status returned
+// to the client whenever they're encountered in the processing of a request.
+// By default, errors are converted with the DefaultErrorEncoder.
+func ServerErrorEncoder(ee ErrorEncoder) ServerOption {
+	return func(s *Server) { s.errorEncoder = ee }
+}
+
 // ServerErrorLogger is used to log non-terminal errors. By default, no errors
 // are logged.
 // Deprecated: Use ServerErrorHandler instead.
@@ -94,6 +106,13 @@ func (s Server) ServeGRPC(ctx context.Context, req interface{}) (retctx context.
 		md = metadata.MD{}
 	}
 
+	// Finalizers see the original error.
+	defer func() {
+		if err != nil {
+			err = s.errorEncoder(ctx, err)
+		}
+	}()
+
 	if len(s.finalizer) > 0 {
 		defer func() {
 			for _, f := range s.finalizer {
@@ -124,7 +143,7 @@ func (s Server) ServeGRPC(ctx context.Context, req interface{}) (retctx context.
 		return ctx, nil, err
 	}
 
-	var mdHeader, mdTrailer metadata.MD
+	mdHeader, mdTrailer := metadata.MD{}, metadata.MD{}
 	for _, f := range s.after {
 		ctx = f(ctx, &mdHeader, &mdTrailer)
 	}
@@ -152,6 +171,65 @@ func (s Server) ServeGRPC(ctx context.Context, req interface{}) (retctx context.
 	return ctx, grpcResp, nil
 }
 
+// ErrorEncoder is responsible for converting an error to the error returned
+// to gRPC, which should carry the gRPC status sent to the client. Users are
+// encouraged to use custom ErrorEncoders to map their own error types to
+// gRPC statuses.
+type ErrorEncoder func(ctx context.Context, err error) error
+
+// DefaultErrorEncoder returns errors that carry a gRPC status as is. If the
+// error wraps an error that implements GRPCStatuser, the provided status is
+// used. If the error, or any error it wraps, implements StatusCoder, the
+// provided code is used, along with the error message. Other errors,
+// including context errors, are returned unchanged, and grpc-go converts them
+// itself: context errors to codes.Canceled and codes.DeadlineExceeded, and
+// others to codes.Unknown.
+//
+// Converted errors wrap the original error, so that finalizers, interceptors
+// and callers of ServeGRPC can still inspect it with errors.Is and errors.As.
+func DefaultErrorEncoder(_ context.Context, err error) error {
+	var (
+		statuser GRPCStatuser
+		coder    StatusCoder
+	)
+	switch {
+	case errors.As(err, &statuser):
+		if _, ok := err.(GRPCStatuser); ok {
+			return err
+		}
+		return statusError{status: statuser.GRPCStatus(), err: err}
+	case errors.As(err, &coder):
+		return statusError{status: status.New(coder.GRPCStatusCode(), err.Error()), err: err}
+	}
+	return err
+}
+
+// statusError is an error carrying a gRPC status, which wraps the error it
+// was converted from.
+type statusError struct {
+	status *status.Status
+	err    error
+}
+
+func (e statusError) Error() string              { return e.err.Error() }
+func (e statusError) Unwrap() error              { return e.err }
+func (e statusError) GRPCStatus() *status.Status { return e.status }
+
+// GRPCStatuser is checked by DefaultErrorEncoder. If an error value implements
+// GRPCStatuser, the provided status, including its code, message and details,
+// is sent to the client. Its method is the one grpc-go itself looks for in
+// errors, e.g. in status.FromError.
+type GRPCStatuser interface {
+	GRPCStatus() *status.Status
+}
+
+// StatusCoder is checked by DefaultErrorEncoder. If an error value implements
+// StatusCoder, the GRPCStatusCode will be used when encoding the error. By
+// default, codes.Unknown is used.
+type StatusCoder interface {
+	GRPCStatusCode() codes.Code
+}
+
 // ServerFinalizerFunc can be used to perform work at the end of an gRPC
 // request, after the response has been written to the client.
 type ServerFinalizerFunc func(ctx context.Context, err error)
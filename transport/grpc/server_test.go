@@ -0,0 +1,202 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kit/kit/transport/baggage"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+)
+
+// notFoundError is a business error that presents itself to gRPC clients.
+type notFoundError struct {
+	resource string
+}
+
+func (e notFoundError) Error() string { return e.resource + " not found" }
+
+func (e notFoundError) GRPCStatus() *status.Status {
+	st, _ := status.New(codes.NotFound, e.Error()).WithDetails(&errdetails.ResourceInfo{ResourceName: e.resource})
+	return st
+}
+
+type invalidError struct{}
+
+func (invalidError) Error() string { return "invalid" }
+
+func (invalidError) GRPCStatusCode() codes.Code { return codes.InvalidArgument }
+
+func TestDefaultErrorEncoder(t *testing.T) {
+	for _, testcase := range []struct {
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{errors.New("boom"), codes.Unknown, "boom"},
+		{status.Error(codes.Unavailable, "later"), codes.Unavailable, "later"},
+		{notFoundError{"book"}, codes.NotFound, "book not found"},
+		{fmt.Errorf("wrapped: %w", notFoundError{"book"}), codes.NotFound, "book not found"},
+		{invalidError{}, codes.InvalidArgument, "invalid"},
+		{context.Canceled, codes.Canceled, "context canceled"},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "wrapped: context deadline exceeded"},
+	} {
+		// grpc-go converts the errors returned by handlers like this.
+		err := kitgrpc.DefaultErrorEncoder(context.Background(), testcase.err)
+		st, ok := status.FromError(err)
+		if !ok {
+			st = status.FromContextError(err)
+		}
+		if want, have := testcase.code, st.Code(); want != have {
+			t.Errorf("%v: want %v, have %v", testcase.err, want, have)
+		}
+		if want, have := testcase.message, st.Message(); want != have {
+			t.Errorf("%v: want %q, have %q", testcase.err, want, have)
+		}
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	var (
+		finalized error
+		server    = kitgrpc.NewServer(
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				return nil, notFoundError{request.(string)}
+			},
+			decodeString, encodeString,
+			kitgrpc.ServerFinalizer(func(_ context.Context, err error) { finalized = err }),
+		)
+		client = kitgrpc.NewClient(startUnaryServer(t, server), "kit.test.Unary", "Unary", encodeString, decodeString, wrapperspb.StringValue{},
+			kitgrpc.ClientErrorDecoder(func(_ context.Context, st *status.Status) error {
+				for _, detail := range st.Details() {
+					if info, ok := detail.(*errdetails.ResourceInfo); ok && st.Code() == codes.NotFound {
+						return notFoundError{info.ResourceName}
+					}
+				}
+				return st.Err()
+			}),
+		)
+	)
+
+	_, err := client.Endpoint()(context.Background(), "book")
+	if want, have := (notFoundError{"book"}), err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := (notFoundError{"book"}), finalized; want != have {
+		t.Errorf("finalizer: want %v, have %v", want, have)
+	}
+
+	// Custom encoders replace the default one.
+	server = kitgrpc.NewServer(
+		func(context.Context, interface{}) (interface{}, error) { return nil, errors.New("boom") },
+		decodeString, encodeString,
+		kitgrpc.ServerErrorEncoder(func(context.Context, error) error { return status.Error(codes.Internal, "oops") }),
+	)
+	client = kitgrpc.NewClient(startUnaryServer(t, server), "kit.test.Unary", "Unary", encodeString, decodeString, wrapperspb.StringValue{})
+	_, err = client.Endpoint()(context.Background(), "book")
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "oops" {
+		t.Errorf("want Internal oops, have %v", err)
+	}
+}
+
+func TestServerErrorChain(t *testing.T) {
+	errBoom := errors.New("boom")
+	for _, testcase := range []struct {
+		err    error
+		target error
+		code   codes.Code
+	}{
+		{fmt.Errorf("loading: %w", errBoom), errBoom, codes.Unknown},
+		{fmt.Errorf("loading: %w", invalidError{}), invalidError{}, codes.InvalidArgument},
+		{fmt.Errorf("loading: %w", notFoundError{"book"}), notFoundError{"book"}, codes.NotFound},
+	} {
+		server := kitgrpc.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { return nil, testcase.err },
+			decodeString, encodeString,
+		)
+		_, _, err := server.ServeGRPC(context.Background(), wrapperspb.String("book"))
+		if !errors.Is(err, testcase.target) {
+			t.Errorf("%v: want errors.Is %v, have %v", testcase.err, testcase.target, err)
+		}
+		if want, have := testcase.code, status.Convert(err).Code(); want != have {
+			t.Errorf("%v: want %v, have %v", testcase.err, want, have)
+		}
+	}
+}
+
+func unaryHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := &wrapperspb.StringValue{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	_, resp, err := srv.(kitgrpc.Handler).ServeGRPC(ctx, req)
+	return resp, err
+}
+
+func startUnaryServer(t *testing.T, handler kitgrpc.Handler) *grpc.ClientConn {
+	t.Helper()
+	var (
+		lis    = bufconn.Listen(1 << 20)
+		server = grpc.NewServer()
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "kit.test.Unary",
+		HandlerType: (*kitgrpc.Handler)(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Unary", Handler: unaryHandler}},
+	}, handler)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestBaggage(t *testing.T) {
+	var (
+		server = kitgrpc.NewServer(
+			func(ctx context.Context, _ interface{}) (interface{}, error) {
+				tenant, _ := baggage.Get(ctx, "X-Tenant-ID")
+				_, secret := baggage.Get(ctx, "X-Secret")
+				return fmt.Sprintf("%s %v", tenant, secret), nil
+			},
+			decodeString, encodeString,
+			kitgrpc.ServerBefore(kitgrpc.ExtractBaggage("X-Tenant-ID", "X-Secret")),
+			kitgrpc.ServerAfter(kitgrpc.InjectResponseBaggage("X-Tenant-ID")),
+		)
+		client = kitgrpc.NewClient(startUnaryServer(t, server), "kit.test.Unary", "Unary", encodeString,
+			func(ctx context.Context, reply interface{}) (interface{}, error) {
+				tenant, _ := baggage.Get(ctx, "x-tenant-id")
+				return reply.(*wrapperspb.StringValue).GetValue() + " " + tenant, nil
+			},
+			wrapperspb.StringValue{},
+			kitgrpc.ClientBefore(kitgrpc.InjectBaggage("X-Tenant-ID")),
+			kitgrpc.ClientAfter(kitgrpc.ExtractResponseBaggage("X-Tenant-ID")),
+		)
+		ctx = baggage.Set(baggage.Set(context.Background(), "X-Tenant-ID", "acme"), "X-Secret", "hunter2")
+	)
+
+	response, err := client.Endpoint()(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme false acme", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,150 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// NewServiceDesc returns a grpc.ServiceDesc for a protobuf service, whose
+// methods are served by the given handlers, so that no binding struct needs
+// to be written for it. Handlers are keyed by full method name, e.g.
+// "/pb.Test/Test", and must all belong to the same service. Unary methods are
+// served by handlers, and streaming methods by streams, which may be nil.
+// Methods without a handler fail with codes.Unimplemented.
+//
+// The service is looked up in the global protobuf registry, so the package
+// generated from its protobuf definition must be linked in. Request messages
+// are of the generated types, or dynamic messages if there are none. Register
+// the returned ServiceDesc with a nil implementation.
+//
+//	desc, err := kitgrpc.NewServiceDesc(map[string]kitgrpc.Handler{
+//		"/pb.Test/Test": kitgrpc.NewServer(endpoint, decodeRequest, encodeResponse),
+//	}, nil)
+//	if err != nil {
+//		return err
+//	}
+//	server.RegisterService(desc, nil)
+func NewServiceDesc(handlers map[string]Handler, streams map[string]StreamHandler) (*grpc.ServiceDesc, error) {
+	var fullMethods []string
+	for fullMethod := range handlers {
+		fullMethods = append(fullMethods, fullMethod)
+	}
+	for fullMethod := range streams {
+		fullMethods = append(fullMethods, fullMethod)
+	}
+	if len(fullMethods) == 0 {
+		return nil, errors.New("no handlers")
+	}
+
+	var service string
+	for _, fullMethod := range fullMethods {
+		s, _, err := splitFullMethod(fullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if service != "" && s != service {
+			return nil, fmt.Errorf("methods of several services: %s and %s", service, s)
+		}
+		service = s
+	}
+
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, fmt.Errorf("service %s: %w", service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+
+	for _, fullMethod := range fullMethods {
+		if _, method, _ := splitFullMethod(fullMethod); sd.Methods().ByName(protoreflect.Name(method)) == nil {
+			return nil, fmt.Errorf("service %s has no method %s", service, method)
+		}
+	}
+
+	desc := &grpc.ServiceDesc{
+		ServiceName: service,
+		HandlerType: (*interface{})(nil),
+		Metadata:    sd.ParentFile().Path(),
+	}
+	for i := 0; i < sd.Methods().Len(); i++ {
+		var (
+			md         = sd.Methods().Get(i)
+			fullMethod = "/" + service + "/" + string(md.Name())
+			streaming  = md.IsStreamingClient() || md.IsStreamingServer()
+		)
+		if h, ok := handlers[fullMethod]; ok {
+			if streaming {
+				return nil, fmt.Errorf("%s is a streaming method", fullMethod)
+			}
+			desc.Methods = append(desc.Methods, grpc.MethodDesc{
+				MethodName: string(md.Name()),
+				Handler:    unaryHandler(h, fullMethod, messageType(md.Input())),
+			})
+		}
+		if h, ok := streams[fullMethod]; ok {
+			if !streaming {
+				return nil, fmt.Errorf("%s is a unary method", fullMethod)
+			}
+			desc.Streams = append(desc.Streams, grpc.StreamDesc{
+				StreamName:    string(md.Name()),
+				Handler:       streamHandler(h),
+				ServerStreams: md.IsStreamingServer(),
+				ClientStreams: md.IsStreamingClient(),
+			})
+		}
+	}
+	return desc, nil
+}
+
+func unaryHandler(h Handler, fullMethod string, mt protoreflect.MessageType) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	serve := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, resp, err := h.ServeGRPC(ctx, req)
+		return resp, err
+	}
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := mt.New().Interface()
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return serve(ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		return interceptor(ctx, req, info, serve)
+	}
+}
+
+func streamHandler(h StreamHandler) grpc.StreamHandler {
+	return func(_ interface{}, stream grpc.ServerStream) error {
+		_, err := h.ServeGRPCStream(stream)
+		return err
+	}
+}
+
+// messageType returns the generated type of the message, if it's linked in,
+// or a dynamic one.
+func messageType(md protoreflect.MessageDescriptor) protoreflect.MessageType {
+	if mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName()); err == nil {
+		return mt
+	}
+	return dynamicpb.NewMessageType(md)
+}
+
+// splitFullMethod splits a full method name, e.g. "/pb.Test/Test", into its
+// service and method names.
+func splitFullMethod(fullMethod string) (service, method string, err error) {
+	i := strings.LastIndex(fullMethod, "/")
+	if !strings.HasPrefix(fullMethod, "/") || i <= 1 || i == len(fullMethod)-1 {
+		return "", "", fmt.Errorf("invalid full method name %q", fullMethod)
+	}
+	return fullMethod[1:i], fullMethod[i+1:], nil
+}
@@ -0,0 +1,129 @@
+package grpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/go-kit/kit/transport/grpc/_grpc_test/pb"
+)
+
+func TestNewServiceDesc(t *testing.T) {
+	var (
+		check = kitgrpc.NewServer(
+			func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+			func(_ context.Context, req interface{}) (interface{}, error) {
+				return req.(*healthpb.HealthCheckRequest).Service, nil
+			},
+			func(_ context.Context, response interface{}) (interface{}, error) {
+				if response == "down" {
+					return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+				}
+				return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+			},
+		)
+		watch = kitgrpc.NewStreamServer(
+			func(ctx context.Context, requests <-chan interface{}, responses chan<- interface{}) error {
+				<-requests
+				for _, st := range []healthpb.HealthCheckResponse_ServingStatus{healthpb.HealthCheckResponse_SERVING, healthpb.HealthCheckResponse_NOT_SERVING} {
+					select {
+					case responses <- st:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				return nil
+			},
+			func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+			func(_ context.Context, st interface{}) (interface{}, error) {
+				return &healthpb.HealthCheckResponse{Status: st.(healthpb.HealthCheckResponse_ServingStatus)}, nil
+			},
+			healthpb.HealthCheckRequest{},
+		)
+	)
+
+	desc, err := kitgrpc.NewServiceDesc(
+		map[string]kitgrpc.Handler{"/grpc.health.v1.Health/Check": check},
+		map[string]kitgrpc.StreamHandler{"/grpc.health.v1.Health/Watch": watch},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		lis    = bufconn.Listen(1 << 20)
+		server = grpc.NewServer(grpc.UnaryInterceptor(kitgrpc.Interceptor))
+	)
+	server.RegisterService(desc, nil)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+
+	response, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := healthpb.HealthCheckResponse_NOT_SERVING, response.Status; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []healthpb.HealthCheckResponse_ServingStatus
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, response.Status)
+	}
+	if want, have := 2, len(statuses); want != have {
+		t.Errorf("want %d statuses, have %d", want, have)
+	}
+
+	// Unrelated services aren't served.
+	_, err = pb.NewTestClient(cc).Test(context.Background(), &pb.TestRequest{})
+	if want, have := codes.Unimplemented, status.Code(err); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestNewServiceDescErrors(t *testing.T) {
+	nop := kitgrpc.NewServer(endpoint.Nop, decodeString, encodeString)
+	for name, handlers := range map[string]map[string]kitgrpc.Handler{
+		"no handlers":      {},
+		"invalid name":     {"pb.Test.Test": nop},
+		"several services": {"/pb.Test/Test": nop, "/grpc.health.v1.Health/Check": nop},
+		"unknown service":  {"/pb.Nope/Test": nop},
+		"unknown method":   {"/pb.Test/Nope": nop},
+		"streaming method": {"/grpc.health.v1.Health/Watch": nop},
+	} {
+		if _, err := kitgrpc.NewServiceDesc(handlers, nil); err == nil {
+			t.Errorf("%s: want error, have none", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// StreamClient wraps a gRPC connection and provides a method that implements
+// StreamEndpoint, for streaming RPCs.
+type StreamClient struct {
+	base Client // the connection, method, codecs and hooks
+}
+
+// NewStreamClient constructs a usable StreamClient for a single remote
+// streaming method. Every request is encoded with enc, and every gRPC
+// response message decoded with dec. Pass a zero-value protobuf message of
+// the RPC response type as the grpcReply argument.
+//
+// StreamClients take the same options as Clients. ClientBefore funcs are
+// executed before the stream is opened, and ClientAfter funcs when the
+// stream ends, with its header and trailer, so that the context they return
+// is passed to the finalizers.
+func NewStreamClient(
+	cc *grpc.ClientConn,
+	serviceName string,
+	method string,
+	enc EncodeRequestFunc,
+	dec DecodeResponseFunc,
+	grpcReply interface{},
+	options ...ClientOption,
+) *StreamClient {
+	return &StreamClient{
+		base: *NewClient(cc, serviceName, method, enc, dec, grpcReply, options...),
+	}
+}
+
+// streamDesc describes every kind of stream: server-streaming RPCs just get a
+// single request, and client-streaming RPCs return a single response.
+var streamDesc = grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+
+// StreamEndpoint returns a usable StreamEndpoint that will open a stream of
+// the gRPC method specified by the client. The stream is half-closed when the
+// requests channel is closed, and the StreamEndpoint returns when the server
+// ends the stream.
+func (c StreamClient) StreamEndpoint() StreamEndpoint {
+	return func(ctx context.Context, requests <-chan interface{}, responses chan<- interface{}) (err error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		if c.base.finalizer != nil {
+			defer func() {
+				for _, f := range c.base.finalizer {
+					f(ctx, err)
+				}
+			}()
+		}
+
+		ctx = context.WithValue(ctx, ContextKeyRequestMethod, c.base.method)
+
+		md := &metadata.MD{}
+		for _, f := range c.base.before {
+			ctx = f(ctx, md)
+		}
+		ctx = metadata.NewOutgoingContext(ctx, *md)
+
+		stream, err := c.base.client.NewStream(ctx, &streamDesc, c.base.method)
+		if err != nil {
+			return c.base.decodeError(ctx, err)
+		}
+
+		sendc := make(chan error, 1)
+		go c.send(ctx, cancel, stream, requests, sendc)
+
+		for {
+			grpcReply := reflect.New(c.base.grpcReply).Interface()
+			if err = stream.RecvMsg(grpcReply); err != nil {
+				break
+			}
+
+			response, err := c.base.dec(ctx, grpcReply)
+			if err != nil {
+				return err
+			}
+
+			select {
+			case responses <- response:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if err != io.EOF {
+			select {
+			case sendErr := <-sendc:
+				return sendErr // the stream was canceled because of it
+			default:
+				return c.base.decodeError(ctx, err)
+			}
+		}
+
+		header, err := stream.Header()
+		if err != nil {
+			return err
+		}
+		for _, f := range c.base.after {
+			ctx = f(ctx, header, stream.Trailer())
+		}
+		return nil
+	}
+}
+
+// send encodes the requests, and sends them on the stream. On failure, it
+// reports the error to errc before canceling the stream, so that it takes
+// precedence over the error of the stream.
+func (c StreamClient) send(ctx context.Context, cancel context.CancelFunc, stream grpc.ClientStream, requests <-chan interface{}, errc chan<- error) {
+	for {
+		select {
+		case request, ok := <-requests:
+			if !ok {
+				stream.CloseSend()
+				return
+			}
+
+			req, err := c.base.enc(ctx, request)
+			if err != nil {
+				errc <- err
+				cancel()
+				return
+			}
+
+			// Sending fails with io.EOF when the stream ends, in which case
+			// the actual error is returned when receiving.
+			if err := stream.SendMsg(req); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// StreamEndpoint is the streaming counterpart of endpoint.Endpoint, used by
+// StreamServer and StreamClient. It receives requests from the requests
+// channel, which is closed when the other side is done sending, and sends
+// responses to the responses channel. It's done sending when it returns, and
+// must not close the responses channel.
+//
+// The same shape serves all kinds of streaming RPCs: server-streaming RPCs
+// carry a single request, and client-streaming RPCs a single response.
+// Implementations should stop when the context is done, and select on it when
+// sending responses.
+type StreamEndpoint func(ctx context.Context, requests <-chan interface{}, responses chan<- interface{}) error
+
+// StreamHandler should be called from the gRPC binding of a streaming method
+// of the service implementation.
+type StreamHandler interface {
+	ServeGRPCStream(stream grpc.ServerStream) (context.Context, error)
+}
+
+// StreamServer wraps a StreamEndpoint and implements StreamHandler.
+type StreamServer struct {
+	base        Server // the codecs and hooks
+	e           StreamEndpoint
+	grpcRequest reflect.Type
+}
+
+// NewStreamServer constructs a new streaming server, which wraps the provided
+// StreamEndpoint and implements the StreamHandler interface. Every gRPC
+// request message is decoded with dec, and every response encoded with enc.
+// Pass a zero-value protobuf message of the RPC request type as the
+// grpcRequest argument.
+//
+// StreamServers take the same options as Servers. ServerBefore funcs are
+// executed when the stream starts, and ServerAfter funcs before the first
+// response is written, or when the endpoint returns if there are none.
+// Finalizers are executed when the stream ends.
+func NewStreamServer(
+	e StreamEndpoint,
+	dec DecodeRequestFunc,
+	enc EncodeResponseFunc,
+	grpcRequest interface{},
+	options ...ServerOption,
+) *StreamServer {
+	return &StreamServer{
+		base:        *NewServer(nil, dec, enc, options...),
+		e:           e,
+		grpcRequest: reflect.TypeOf(reflect.Indirect(reflect.ValueOf(grpcRequest)).Interface()),
+	}
+}
+
+// ServeGRPCStream implements the StreamHandler interface.
+func (s StreamServer) ServeGRPCStream(stream grpc.ServerStream) (retctx context.Context, err error) {
+	ctx := stream.Context()
+
+	// Retrieve gRPC metadata.
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.MD{}
+	}
+
+	// Finalizers see the original error.
+	defer func() {
+		if err != nil {
+			err = s.base.errorEncoder(ctx, err)
+		}
+	}()
+
+	if len(s.base.finalizer) > 0 {
+		defer func() {
+			for _, f := range s.base.finalizer {
+				f(ctx, err)
+			}
+		}()
+	}
+
+	for _, f := range s.base.before {
+		ctx = f(ctx, md)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		requests  = make(chan interface{})
+		responses = make(chan interface{})
+		recvc     = make(chan error, 1)
+		endpointc = make(chan error, 1)
+	)
+	go s.receive(ctx, cancel, stream, requests, recvc)
+	go func(ctx context.Context) {
+		endpointc <- s.e(ctx, requests, responses)
+		close(responses)
+	}(ctx)
+
+	var (
+		mdHeader  = metadata.MD{}
+		mdTrailer = metadata.MD{}
+		afterDone bool
+		sendErr   error
+	)
+	for response := range responses {
+		if sendErr != nil {
+			continue // drain until the endpoint notices the cancellation
+		}
+		if !afterDone {
+			for _, f := range s.base.after {
+				ctx = f(ctx, &mdHeader, &mdTrailer)
+			}
+			afterDone = true
+			if len(mdHeader) > 0 {
+				if sendErr = stream.SendHeader(mdHeader); sendErr != nil {
+					cancel()
+					continue
+				}
+			}
+		}
+		if sendErr = s.send(ctx, stream, response); sendErr != nil {
+			cancel()
+		}
+	}
+
+	err = <-endpointc
+	if sendErr != nil {
+		err = sendErr
+	}
+	select {
+	case recvErr := <-recvc:
+		if recvErr != nil {
+			err = recvErr // the endpoint was canceled because of it
+		}
+	default:
+	}
+	if err != nil {
+		s.base.errorHandler.Handle(ctx, err)
+		return ctx, err
+	}
+
+	if !afterDone {
+		for _, f := range s.base.after {
+			ctx = f(ctx, &mdHeader, &mdTrailer)
+		}
+		if len(mdHeader) > 0 {
+			if err = stream.SendHeader(mdHeader); err != nil {
+				s.base.errorHandler.Handle(ctx, err)
+				return ctx, err
+			}
+		}
+	}
+
+	if len(mdTrailer) > 0 {
+		stream.SetTrailer(mdTrailer)
+	}
+
+	return ctx, nil
+}
+
+// receive decodes the request messages of the stream, and sends them to the
+// endpoint. It closes requests when the client is done sending. On failure,
+// it reports the error to errc before canceling the stream, so that it takes
+// precedence over the error of the endpoint.
+func (s StreamServer) receive(ctx context.Context, cancel context.CancelFunc, stream grpc.ServerStream, requests chan<- interface{}, errc chan<- error) {
+	defer close(requests)
+	for {
+		grpcReq := reflect.New(s.grpcRequest).Interface()
+		if err := stream.RecvMsg(grpcReq); err == io.EOF {
+			errc <- nil
+			return
+		} else if err != nil {
+			if ctx.Err() == nil {
+				errc <- err
+				cancel()
+			}
+			return
+		}
+
+		request, err := s.base.dec(ctx, grpcReq)
+		if err != nil {
+			errc <- err
+			cancel()
+			return
+		}
+
+		select {
+		case requests <- request:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s StreamServer) send(ctx context.Context, stream grpc.ServerStream, response interface{}) error {
+	grpcResp, err := s.base.enc(ctx, response)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(grpcResp)
+}
+
+// StreamInterceptor is a grpc StreamInterceptor that injects the method name
+// into the context of the stream, like Interceptor does for unary calls.
+// Like this: `grpc.NewServer(grpc.StreamInterceptor(kitgrpc.StreamInterceptor))`
+func StreamInterceptor(
+	srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	ctx := context.WithValue(ss.Context(), ContextKeyRequestMethod, info.FullMethod)
+	return handler(srv, contextServerStream{ss, ctx})
+}
+
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextServerStream) Context() context.Context { return s.ctx }
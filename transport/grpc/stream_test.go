@@ -0,0 +1,213 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+)
+
+type streamContextKey string
+
+// upper echoes every request in upper case, prefixed with the ID the server
+// extracted from the request metadata.
+func upper(ctx context.Context, requests <-chan interface{}, responses chan<- interface{}) error {
+	id, _ := ctx.Value(streamContextKey("id")).(string)
+	for request := range requests {
+		s := request.(string)
+		if s == "fail" {
+			return status.Error(codes.InvalidArgument, "failing on request")
+		}
+		select {
+		case responses <- id + strings.ToUpper(s):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// words sends every word of a single request as a separate response.
+func words(ctx context.Context, requests <-chan interface{}, responses chan<- interface{}) error {
+	for _, word := range strings.Fields((<-requests).(string)) {
+		select {
+		case responses <- word:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func decodeString(_ context.Context, msg interface{}) (interface{}, error) {
+	return msg.(*wrapperspb.StringValue).GetValue(), nil
+}
+
+func encodeString(_ context.Context, s interface{}) (interface{}, error) {
+	return wrapperspb.String(s.(string)), nil
+}
+
+func streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	_, err := srv.(kitgrpc.StreamHandler).ServeGRPCStream(stream)
+	return err
+}
+
+func startStreamServer(t *testing.T, handler kitgrpc.StreamHandler) *grpc.ClientConn {
+	t.Helper()
+	var (
+		lis    = bufconn.Listen(1 << 20)
+		server = grpc.NewServer(grpc.StreamInterceptor(kitgrpc.StreamInterceptor))
+	)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "kit.test.Stream",
+		HandlerType: (*kitgrpc.StreamHandler)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Stream",
+			Handler:       streamHandler,
+			ServerStreams: true,
+			ClientStreams: true,
+		}},
+	}, handler)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return cc
+}
+
+func TestStreamBidi(t *testing.T) {
+	var (
+		serverMethod string
+		serverDone   = make(chan error, 1)
+		clientCtx    context.Context
+	)
+	server := kitgrpc.NewStreamServer(upper, decodeString, encodeString, wrapperspb.StringValue{},
+		kitgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+			serverMethod, _ = ctx.Value(kitgrpc.ContextKeyRequestMethod).(string)
+			if id := md.Get("x-id"); len(id) > 0 {
+				ctx = context.WithValue(ctx, streamContextKey("id"), id[0]+":")
+			}
+			return ctx
+		}),
+		kitgrpc.ServerAfter(kitgrpc.SetResponseHeader("x-header", "h"), kitgrpc.SetResponseTrailer("x-trailer", "t")),
+		kitgrpc.ServerFinalizer(func(_ context.Context, err error) { serverDone <- err }),
+	)
+	client := kitgrpc.NewStreamClient(startStreamServer(t, server), "kit.test.Stream", "Stream", encodeString, decodeString, wrapperspb.StringValue{},
+		kitgrpc.ClientBefore(kitgrpc.SetRequestHeader("x-id", "42")),
+		kitgrpc.ClientAfter(func(ctx context.Context, header metadata.MD, trailer metadata.MD) context.Context {
+			ctx = context.WithValue(ctx, streamContextKey("header"), strings.Join(header.Get("x-header"), ""))
+			return context.WithValue(ctx, streamContextKey("trailer"), strings.Join(trailer.Get("x-trailer"), ""))
+		}),
+		kitgrpc.ClientFinalizer(func(ctx context.Context, _ error) { clientCtx = ctx }),
+	)
+
+	var (
+		requests  = make(chan interface{})
+		responses = make(chan interface{})
+		errc      = make(chan error, 1)
+	)
+	go func() {
+		errc <- client.StreamEndpoint()(context.Background(), requests, responses)
+	}()
+	for _, s := range []string{"a", "b", "c"} {
+		requests <- s
+		if want, have := "42:"+strings.ToUpper(s), <-responses; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	}
+	close(requests)
+
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-serverDone; err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "/kit.test.Stream/Stream", serverMethod; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "h", clientCtx.Value(streamContextKey("header")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "t", clientCtx.Value(streamContextKey("trailer")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestStreamServerStreaming(t *testing.T) {
+	var (
+		server    = kitgrpc.NewStreamServer(words, decodeString, encodeString, wrapperspb.StringValue{})
+		client    = kitgrpc.NewStreamClient(startStreamServer(t, server), "kit.test.Stream", "Stream", encodeString, decodeString, wrapperspb.StringValue{})
+		requests  = make(chan interface{}, 1)
+		responses = make(chan interface{}, 10)
+	)
+	requests <- "the quick brown fox"
+	close(requests)
+
+	if err := client.StreamEndpoint()(context.Background(), requests, responses); err != nil {
+		t.Fatal(err)
+	}
+	close(responses)
+
+	var have []string
+	for response := range responses {
+		have = append(have, response.(string))
+	}
+	if want := "the quick brown fox"; want != strings.Join(have, " ") {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	var (
+		serverDone = make(chan error, 1)
+		server     = kitgrpc.NewStreamServer(upper, decodeString, encodeString, wrapperspb.StringValue{},
+			kitgrpc.ServerFinalizer(func(_ context.Context, err error) { serverDone <- err }),
+		)
+		cc        = startStreamServer(t, server)
+		client    = kitgrpc.NewStreamClient(cc, "kit.test.Stream", "Stream", encodeString, decodeString, wrapperspb.StringValue{})
+		requests  = make(chan interface{}, 2)
+		responses = make(chan interface{}, 2)
+	)
+
+	// Endpoint errors end the stream.
+	requests <- "a"
+	requests <- "fail"
+	err := client.StreamEndpoint()(context.Background(), requests, responses)
+	if want, have := codes.InvalidArgument, status.Code(err); want != have {
+		t.Errorf("want %v, have %v (%v)", want, have, err)
+	}
+	if want, have := codes.InvalidArgument, status.Code(<-serverDone); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	// Codec errors take precedence over the resulting cancellation.
+	myErr := errors.New("can't encode")
+	failing := kitgrpc.NewStreamClient(cc, "kit.test.Stream", "Stream",
+		func(context.Context, interface{}) (interface{}, error) { return nil, myErr },
+		decodeString, wrapperspb.StringValue{},
+	)
+	requests = make(chan interface{}, 1)
+	requests <- "a"
+	if want, have := myErr, failing.StreamEndpoint()(context.Background(), requests, responses); want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
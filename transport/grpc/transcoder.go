@@ -0,0 +1,528 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Route maps HTTP requests to a gRPC method, like a google.api.http rule.
+type Route struct {
+	// Method is the HTTP method, e.g. "GET".
+	Method string
+
+	// Path is the path template, e.g. "/v1/{name=shelves/*/books/*}", with
+	// the syntax of google.api.http rules. Variables are bound to fields of
+	// the request message.
+	Path string
+
+	// FullMethod is the full name of the gRPC method, e.g.
+	// "/pb.Library/GetBook".
+	FullMethod string
+
+	// Body is the field of the request message bound to the request body,
+	// "*" for the whole message, or empty if the request has no body. Fields
+	// not bound to the path or the body are bound to query parameters.
+	Body string
+
+	// ResponseBody is the field of the response message written to the
+	// response body, or empty for the whole message.
+	ResponseBody string
+}
+
+// Transcoder serves gRPC Servers over HTTP, encoding request and response
+// messages as JSON with protojson, like gRPC-Gateway does. It implements
+// http.Handler.
+//
+// The Servers are invoked with some of the HTTP request headers as incoming
+// metadata, selected by the header matcher, so their ServerBefore funcs work
+// the same for both transports. The metadata set by their ServerAfter funcs is
+// written as response headers, prefixed with "Grpc-Metadata-" and
+// "Grpc-Trailer-". Errors are written as a JSON google.rpc.Status, with the
+// HTTP status matching their gRPC status code.
+type Transcoder struct {
+	routes        []route
+	headerMatcher func(key string) (string, bool)
+	maxBodySize   int64
+}
+
+// TranscoderOption sets an optional parameter for transcoders.
+type TranscoderOption func(*transcoderOptions)
+
+type transcoderOptions struct {
+	routes        []Route
+	headerMatcher func(key string) (string, bool)
+	maxBodySize   int64
+}
+
+// TranscoderRoutes adds explicit routes to the ones declared with
+// google.api.http annotations in the protobuf definition of the methods.
+func TranscoderRoutes(routes ...Route) TranscoderOption {
+	return func(o *transcoderOptions) { o.routes = append(o.routes, routes...) }
+}
+
+// TranscoderHeaderMatcher sets the func selecting the HTTP request headers
+// passed to the Servers as incoming metadata. It's called with the canonical
+// key of every header, and returns the metadata key to pass it as, and
+// whether to pass it at all. By default, DefaultHeaderMatcher is used.
+func TranscoderHeaderMatcher(f func(key string) (string, bool)) TranscoderOption {
+	return func(o *transcoderOptions) { o.headerMatcher = f }
+}
+
+// TranscoderMaxBodySize sets the maximum size of request bodies, in bytes.
+// Larger requests fail with codes.InvalidArgument. By default, bodies are
+// limited to 4 MiB, the default maximum size of messages received by gRPC
+// servers.
+func TranscoderMaxBodySize(n int64) TranscoderOption {
+	return func(o *transcoderOptions) { o.maxBodySize = n }
+}
+
+// MetadataHeaderPrefix is the prefix of the HTTP request headers passed to
+// the Servers as incoming metadata by DefaultHeaderMatcher, without the
+// prefix, e.g. "Grpc-Metadata-X-User" as "x-user".
+const MetadataHeaderPrefix = "Grpc-Metadata-"
+
+// DefaultHeaderMatcher passes the standard HTTP request headers, e.g.
+// Authorization, and the headers prefixed with MetadataHeaderPrefix, as
+// gRPC-Gateway does. Other headers aren't passed, since HTTP clients and
+// proxies set many that aren't meant for the service.
+func DefaultHeaderMatcher(key string) (string, bool) {
+	if permanentHeaders[key] {
+		return strings.ToLower(key), true
+	}
+	if strings.HasPrefix(key, MetadataHeaderPrefix) {
+		return strings.ToLower(strings.TrimPrefix(key, MetadataHeaderPrefix)), true
+	}
+	return "", false
+}
+
+// permanentHeaders are the standard HTTP request headers, by canonical key.
+var permanentHeaders = map[string]bool{
+	"Accept":              true,
+	"Accept-Charset":      true,
+	"Accept-Language":     true,
+	"Accept-Ranges":       true,
+	"Authorization":       true,
+	"Cache-Control":       true,
+	"Content-Type":        true,
+	"Cookie":              true,
+	"Date":                true,
+	"Expect":              true,
+	"From":                true,
+	"Host":                true,
+	"If-Match":            true,
+	"If-Modified-Since":   true,
+	"If-None-Match":       true,
+	"If-Unmodified-Since": true,
+	"Max-Forwards":        true,
+	"Origin":              true,
+	"Pragma":              true,
+	"Referer":             true,
+	"User-Agent":          true,
+	"Via":                 true,
+	"Warning":             true,
+}
+
+type route struct {
+	Route
+	handler  Handler
+	template *pathTemplate
+	input    protoreflect.MessageType
+}
+
+// NewTranscoder returns a Transcoder serving the handlers, keyed by full
+// method name, e.g. "/pb.Library/GetBook", over HTTP. Handlers are routed
+// according to the google.api.http annotations of their methods, and the
+// explicit routes passed as options. When the templates of several routes
+// match a request, the one with a literal segment where the others have a
+// wildcard is preferred, e.g. "/v1/books/latest" over "/v1/books/{id}". The methods are looked up in the global
+// protobuf registry, so the packages generated from their protobuf
+// definitions must be linked in.
+func NewTranscoder(handlers map[string]Handler, options ...TranscoderOption) (*Transcoder, error) {
+	opts := transcoderOptions{
+		headerMatcher: DefaultHeaderMatcher,
+		maxBodySize:   4 << 20,
+	}
+	for _, option := range options {
+		option(&opts)
+	}
+
+	fullMethods := make([]string, 0, len(handlers))
+	for fullMethod := range handlers {
+		fullMethods = append(fullMethods, fullMethod)
+	}
+	sort.Strings(fullMethods)
+
+	routes := opts.routes
+	for _, fullMethod := range fullMethods {
+		md, err := findMethod(fullMethod)
+		if err != nil {
+			return nil, err
+		}
+		rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			routes = append(routes, ruleRoute(fullMethod, r))
+		}
+	}
+
+	t := &Transcoder{
+		headerMatcher: opts.headerMatcher,
+		maxBodySize:   opts.maxBodySize,
+	}
+	for _, r := range routes {
+		handler, ok := handlers[r.FullMethod]
+		if !ok {
+			return nil, fmt.Errorf("no handler for %s", r.FullMethod)
+		}
+		md, err := findMethod(r.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			return nil, fmt.Errorf("%s is a streaming method", r.FullMethod)
+		}
+		template, err := parsePathTemplate(r.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.FullMethod, err)
+		}
+		if r.Body != "" && r.Body != "*" && md.Input().Fields().ByName(protoreflect.Name(r.Body)) == nil {
+			return nil, fmt.Errorf("%s: no body field %q", r.FullMethod, r.Body)
+		}
+		if r.ResponseBody != "" && md.Output().Fields().ByName(protoreflect.Name(r.ResponseBody)) == nil {
+			return nil, fmt.Errorf("%s: no response body field %q", r.FullMethod, r.ResponseBody)
+		}
+		t.routes = append(t.routes, route{
+			Route:    r,
+			handler:  handler,
+			template: template,
+			input:    messageType(md.Input()),
+		})
+	}
+
+	// Requests are served by the first matching route, so templates with
+	// literal segments are tried before the overlapping ones with wildcards.
+	sort.SliceStable(t.routes, func(i, j int) bool {
+		return t.routes[i].template.precedes(t.routes[j].template)
+	})
+	return t, nil
+}
+
+func findMethod(fullMethod string) (protoreflect.MethodDescriptor, error) {
+	service, method, err := splitFullMethod(fullMethod)
+	if err != nil {
+		return nil, err
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service + "." + method))
+	if err != nil {
+		return nil, fmt.Errorf("method %s: %w", fullMethod, err)
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a method", fullMethod)
+	}
+	return md, nil
+}
+
+func ruleRoute(fullMethod string, rule *annotations.HttpRule) Route {
+	r := Route{FullMethod: fullMethod, Body: rule.GetBody(), ResponseBody: rule.GetResponseBody()}
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		r.Method, r.Path = http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		r.Method, r.Path = http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		r.Method, r.Path = http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		r.Method, r.Path = http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		r.Method, r.Path = http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		r.Method, r.Path = p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return r
+}
+
+// ServeHTTP implements http.Handler.
+func (t *Transcoder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	for _, rt := range t.routes {
+		if rt.Method != r.Method {
+			continue
+		}
+		if vars, ok := rt.template.match(r.URL.EscapedPath()); ok {
+			t.serve(w, r, rt, vars)
+			return
+		}
+	}
+	writeStatus(w, status.New(codes.NotFound, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path)))
+}
+
+func (t *Transcoder) serve(w http.ResponseWriter, r *http.Request, rt route, vars map[string]string) {
+	r.Body = http.MaxBytesReader(w, r.Body, t.maxBodySize)
+	req, err := rt.decode(r, vars)
+	if err != nil {
+		writeStatus(w, status.New(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	md := metadata.MD{}
+	for key, values := range r.Header {
+		if key, ok := t.headerMatcher(key); ok {
+			md.Append(key, values...)
+		}
+	}
+	stream := &transcoderStream{method: rt.FullMethod}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	ctx = context.WithValue(ctx, ContextKeyRequestMethod, rt.FullMethod)
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+
+	_, resp, err := rt.handler.ServeGRPC(ctx, req)
+	if err != nil {
+		// Convert errors the way grpc-go does, since DefaultErrorEncoder
+		// leaves context errors to it.
+		st, ok := status.FromError(err)
+		if !ok {
+			st = status.FromContextError(err)
+		}
+		writeStatus(w, st)
+		return
+	}
+
+	msg, ok := resp.(proto.Message)
+	if !ok {
+		writeStatus(w, status.Newf(codes.Internal, "response is a %T, not a protobuf message", resp))
+		return
+	}
+	var body proto.Message = msg
+	if rt.ResponseBody != "" {
+		fd := msg.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(rt.ResponseBody))
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			writeStatus(w, status.Newf(codes.Internal, "response body field %s is not a message", fd.Name()))
+			return
+		}
+		body = msg.ProtoReflect().Get(fd).Message().Interface()
+	}
+	buf, err := protojson.Marshal(body)
+	if err != nil {
+		writeStatus(w, status.New(codes.Internal, err.Error()))
+		return
+	}
+
+	writeMetadata(w.Header(), "Grpc-Metadata-", stream.header)
+	writeMetadata(w.Header(), "Grpc-Trailer-", stream.trailer)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf)
+}
+
+// decode returns the request message, with the fields bound to the path
+// variables, the body and the query parameters.
+func (rt route) decode(r *http.Request, vars map[string]string) (proto.Message, error) {
+	req := rt.input.New()
+
+	switch rt.Body {
+	case "":
+	case "*":
+		if err := unmarshalBody(r.Body, req.Interface()); err != nil {
+			return nil, err
+		}
+	default:
+		fd := req.Descriptor().Fields().ByName(protoreflect.Name(rt.Body))
+		if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+			return nil, fmt.Errorf("body field %s is not a message", fd.Name())
+		}
+		if err := unmarshalBody(r.Body, req.Mutable(fd).Message().Interface()); err != nil {
+			return nil, err
+		}
+	}
+
+	for path, value := range vars {
+		if err := setField(req, path, []string{value}); err != nil {
+			return nil, err
+		}
+	}
+
+	if rt.Body == "*" {
+		return req.Interface(), nil // no field is left for query parameters
+	}
+	for path, values := range r.URL.Query() {
+		if _, ok := vars[path]; ok {
+			continue
+		}
+		if rt.Body != "" && (path == rt.Body || strings.HasPrefix(path, rt.Body+".")) {
+			continue
+		}
+		if err := setField(req, path, values); err != nil {
+			return nil, err
+		}
+	}
+	return req.Interface(), nil
+}
+
+func unmarshalBody(r io.Reader, msg proto.Message) error {
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	return protojson.Unmarshal(buf, msg)
+}
+
+// setField sets the field at the dot-separated path, e.g. "book.id", to the
+// values, parsed according to the type of the field.
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("no field %q in %s", path, msg.Descriptor().FullName())
+		}
+		if i < len(names)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field %q is not a message", strings.Join(names[:i+1], "."))
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+
+		if fd.IsMap() || fd.Message() != nil {
+			return fmt.Errorf("field %q can't be bound to a parameter", path)
+		}
+		if fd.IsList() {
+			list := msg.Mutable(fd).List()
+			for _, s := range values {
+				v, err := parseScalar(fd, s)
+				if err != nil {
+					return fmt.Errorf("field %q: %w", path, err)
+				}
+				list.Append(v)
+			}
+			return nil
+		}
+		if len(values) != 1 {
+			return fmt.Errorf("field %q takes a single value", path)
+		}
+		v, err := parseScalar(fd, values[0])
+		if err != nil {
+			return fmt.Errorf("field %q: %w", path, err)
+		}
+		msg.Set(fd, v)
+	}
+	return nil
+}
+
+// parseScalar parses the value of a scalar field, or of an element of a
+// repeated one, from a path variable or query parameter.
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	if fd.Kind() == protoreflect.StringKind {
+		return protoreflect.ValueOfString(s), nil
+	}
+
+	// Unmarshal a message of the same type, with only that field set. The
+	// JSON mapping accepts quoted values for all scalar types but booleans.
+	value := fmt.Sprintf("%q", s)
+	if fd.Kind() == protoreflect.BoolKind {
+		value = s
+	}
+	if fd.IsList() {
+		value = "[" + value + "]"
+	}
+	tmp := messageType(fd.ContainingMessage()).New()
+	if err := protojson.Unmarshal([]byte(fmt.Sprintf("{%q: %s}", fd.JSONName(), value)), tmp.Interface()); err != nil {
+		return protoreflect.Value{}, fmt.Errorf("invalid value %q", s)
+	}
+	if fd.IsList() {
+		return tmp.Get(fd).List().Get(0), nil
+	}
+	return tmp.Get(fd), nil
+}
+
+func writeMetadata(h http.Header, prefix string, md metadata.MD) {
+	for key, values := range md {
+		for _, value := range values {
+			h.Add(prefix+textproto.CanonicalMIMEHeaderKey(key), value)
+		}
+	}
+}
+
+// writeStatus writes the status as a JSON google.rpc.Status.
+func writeStatus(w http.ResponseWriter, st *status.Status) {
+	buf, err := protojson.Marshal(st.Proto())
+	if err != nil {
+		buf = []byte(fmt.Sprintf(`{"code": %d, "message": %q}`, codes.Internal, err.Error()))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatusFromCode(st.Code()))
+	w.Write(buf)
+}
+
+// HTTPStatusFromCode returns the HTTP status corresponding to the gRPC status
+// code, as documented in google/rpc/code.proto.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default: // Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
+
+// transcoderStream collects the metadata set by the ServerAfter funcs of a
+// Server, in place of a gRPC stream.
+type transcoderStream struct {
+	method  string
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *transcoderStream) Method() string { return s.method }
+
+func (s *transcoderStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transcoderStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *transcoderStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// pathTemplate is a parsed path template of a google.api.http rule:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	Verb     = ":" LITERAL ;
+type pathTemplate struct {
+	segments []templateSegment
+	verb     string
+}
+
+type templateSegment struct {
+	literal  string // if neither wildcard nor deep
+	wildcard bool   // "*", a single path segment
+	deep     bool   // "**", the rest of the path
+	variable string // the field path the segment is bound to, if any
+}
+
+func parsePathTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("path template %q doesn't start with /", template)
+	}
+
+	t := &pathTemplate{}
+	s := template[1:]
+	if i := strings.LastIndex(s, ":"); i > strings.LastIndex(s, "/") && i > strings.LastIndex(s, "}") {
+		s, t.verb = s[:i], s[i+1:]
+	}
+
+	for s != "" {
+		if s[0] == '{' {
+			end := strings.Index(s, "}")
+			if end < 0 {
+				return nil, fmt.Errorf("path template %q has an unterminated variable", template)
+			}
+			variable, pattern := s[1:end], "*"
+			if i := strings.Index(variable, "="); i >= 0 {
+				variable, pattern = variable[:i], variable[i+1:]
+			}
+			if variable == "" {
+				return nil, fmt.Errorf("path template %q has an unnamed variable", template)
+			}
+			for _, p := range strings.Split(pattern, "/") {
+				segment := parseTemplateSegment(p)
+				segment.variable = variable
+				t.segments = append(t.segments, segment)
+			}
+			s = s[end+1:]
+		} else {
+			end := strings.Index(s, "/")
+			if end < 0 {
+				end = len(s)
+			}
+			t.segments = append(t.segments, parseTemplateSegment(s[:end]))
+			s = s[end:]
+		}
+
+		if s != "" {
+			if s[0] != '/' || len(s) == 1 {
+				return nil, fmt.Errorf("path template %q is malformed", template)
+			}
+			s = s[1:]
+		}
+	}
+
+	for i, segment := range t.segments {
+		if segment.deep && i != len(t.segments)-1 {
+			return nil, fmt.Errorf("path template %q has ** before its end", template)
+		}
+		if !segment.wildcard && !segment.deep && segment.literal == "" {
+			return nil, fmt.Errorf("path template %q has an empty segment", template)
+		}
+	}
+	return t, nil
+}
+
+func parseTemplateSegment(s string) templateSegment {
+	switch s {
+	case "*":
+		return templateSegment{wildcard: true}
+	case "**":
+		return templateSegment{deep: true}
+	default:
+		return templateSegment{literal: s}
+	}
+}
+
+// match returns the values of the variables of the template, if the escaped
+// path matches it. The path is split into segments before unescaping them, so
+// that variables may contain escaped slashes.
+func (t *pathTemplate) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	path = path[1:]
+	if t.verb != "" {
+		if !strings.HasSuffix(path, ":"+t.verb) {
+			return nil, false
+		}
+		path = strings.TrimSuffix(path, ":"+t.verb)
+	}
+
+	var (
+		parts  = strings.Split(path, "/")
+		values = map[string][]string{}
+	)
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil, false
+		}
+		parts[i] = unescaped
+	}
+	for i, segment := range t.segments {
+		if segment.deep {
+			if segment.variable != "" {
+				values[segment.variable] = append(values[segment.variable], parts[i:]...)
+			}
+			parts = parts[:i]
+			break
+		}
+		if i >= len(parts) || parts[i] == "" {
+			return nil, false
+		}
+		if !segment.wildcard && parts[i] != segment.literal {
+			return nil, false
+		}
+		if segment.variable != "" {
+			values[segment.variable] = append(values[segment.variable], parts[i])
+		}
+	}
+	if len(t.segments) == 0 || !t.segments[len(t.segments)-1].deep {
+		if len(parts) != len(t.segments) {
+			return nil, false
+		}
+	}
+
+	vars := make(map[string]string, len(values))
+	for variable, parts := range values {
+		vars[variable] = strings.Join(parts, "/")
+	}
+	return vars, true
+}
+
+// precedes reports whether the template should be tried before the other one,
+// because it's more specific: at the first segment where they differ, literal
+// segments precede single wildcards, which precede deep ones. Otherwise,
+// templates with a verb precede those without.
+func (t *pathTemplate) precedes(other *pathTemplate) bool {
+	for i := 0; i < len(t.segments) && i < len(other.segments); i++ {
+		if a, b := t.segments[i].rank(), other.segments[i].rank(); a != b {
+			return a < b
+		}
+	}
+	return t.verb != "" && other.verb == ""
+}
+
+func (s templateSegment) rank() int {
+	switch {
+	case s.deep:
+		return 2
+	case s.wildcard:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,394 @@
+package grpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+)
+
+// library is a service declared at runtime, since protoc isn't available to
+// generate one with google.api.http annotations. Its messages are dynamic.
+var library = func() protoreflect.FileDescriptor {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, repeated bool, typeName string) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	var (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		i64 = descriptorpb.FieldDescriptorProto_TYPE_INT64
+		b   = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	httpRule := func(rule *annotations.HttpRule) *descriptorpb.MethodOptions {
+		options := &descriptorpb.MethodOptions{}
+		proto.SetExtension(options, annotations.E_Http, rule)
+		return options
+	}
+
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("kit/transcoder_test.proto"),
+		Package: proto.String("kittest"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Book"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str, false, ""),
+				field("id", 2, i64, false, ""),
+				field("tags", 3, str, true, ""),
+				field("draft", 4, b, false, ""),
+			}},
+			{Name: proto.String("GetBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str, false, ""),
+				field("revision", 2, i64, false, ""),
+				field("tags", 3, str, true, ""),
+			}},
+			{Name: proto.String("UpdateBookRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("name", 1, str, false, ""),
+				field("book", 2, msg, false, ".kittest.Book"),
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Library"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name: proto.String("GetBook"), InputType: proto.String(".kittest.GetBookRequest"), OutputType: proto.String(".kittest.Book"),
+					Options: httpRule(&annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=shelves/*/books/*}"}}),
+				},
+				{
+					Name: proto.String("UpdateBook"), InputType: proto.String(".kittest.UpdateBookRequest"), OutputType: proto.String(".kittest.Book"),
+					Options: httpRule(&annotations.HttpRule{Pattern: &annotations.HttpRule_Patch{Patch: "/v1/{name=shelves/*/books/*}"}, Body: "book"}),
+				},
+				{Name: proto.String("CreateBook"), InputType: proto.String(".kittest.Book"), OutputType: proto.String(".kittest.Book")},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+		panic(err)
+	}
+	return fd
+}()
+
+// newBook returns a dynamic Book message with the given fields set.
+func newBook(set func(m protoreflect.Message, fields protoreflect.FieldDescriptors)) proto.Message {
+	md := library.Messages().ByName("Book")
+	m := dynamicpb.NewMessage(md)
+	set(m, md.Fields())
+	return m
+}
+
+func TestTranscoder(t *testing.T) {
+	var (
+		decode = func(_ context.Context, req interface{}) (interface{}, error) { return req, nil }
+		encode = func(_ context.Context, resp interface{}) (interface{}, error) { return resp, nil }
+
+		getBook = kitgrpc.NewServer(
+			func(ctx context.Context, request interface{}) (interface{}, error) {
+				req := request.(proto.Message).ProtoReflect()
+				fields := req.Descriptor().Fields()
+				name := req.Get(fields.ByName("name")).String()
+				if name == "shelves/1/books/404" {
+					return nil, status.Error(codes.NotFound, "no such book")
+				}
+				return newBook(func(m protoreflect.Message, f protoreflect.FieldDescriptors) {
+					m.Set(f.ByName("name"), protoreflect.ValueOfString(name+"@"+ctx.Value(streamContextKey("user")).(string)))
+					m.Set(f.ByName("id"), req.Get(fields.ByName("revision")))
+					tags, list := req.Get(fields.ByName("tags")).List(), m.Mutable(f.ByName("tags")).List()
+					for i := 0; i < tags.Len(); i++ {
+						list.Append(tags.Get(i))
+					}
+				}), nil
+			},
+			decode, encode,
+			kitgrpc.ServerBefore(func(ctx context.Context, md metadata.MD) context.Context {
+				return context.WithValue(ctx, streamContextKey("user"), strings.Join(md.Get("x-user"), ""))
+			}),
+			kitgrpc.ServerAfter(kitgrpc.SetResponseHeader("x-served-by", "kit")),
+		)
+		updateBook = kitgrpc.NewServer(
+			func(_ context.Context, request interface{}) (interface{}, error) {
+				req := request.(proto.Message).ProtoReflect()
+				return req.Get(req.Descriptor().Fields().ByName("book")).Message().Interface(), nil
+			},
+			decode, encode,
+		)
+		createBook = kitgrpc.NewServer(endpointEcho, decode, encode)
+	)
+
+	transcoder, err := kitgrpc.NewTranscoder(map[string]kitgrpc.Handler{
+		"/kittest.Library/GetBook":    getBook,
+		"/kittest.Library/UpdateBook": updateBook,
+		"/kittest.Library/CreateBook": createBook,
+	}, kitgrpc.TranscoderRoutes(kitgrpc.Route{Method: http.MethodPost, Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook", Body: "*"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(transcoder)
+	defer server.Close()
+
+	for _, testcase := range []struct {
+		method, path, body string
+		code               int
+		response           string
+	}{
+		{"GET", "/v1/shelves/1/books/2?revision=3&tags=a&tags=b", "", 200, `{"name":"shelves/1/books/2@alice","id":"3","tags":["a","b"]}`},
+		{"GET", "/v1/shelves/1/books/a%2Fb%20c", "", 200, `{"name":"shelves/1/books/a/b c@alice"}`},
+		{"GET", "/v1/shelves/1/books/404", "", 404, `{"code":5,"message":"no such book"}`},
+		{"GET", "/v1/shelves/1/books/2?revision=x", "", 400, ""},
+		{"GET", "/v1/shelves/1", "", 404, ""},
+		{"PATCH", "/v1/shelves/1/books/2", `{"name":"renamed","draft":true}`, 200, `{"name":"renamed","draft":true}`},
+		{"POST", "/v1/books", `{"name":"new","id":"7"}`, 200, `{"name":"new","id":"7"}`},
+		{"POST", "/v1/books", `{"name":`, 400, ""},
+	} {
+		req, _ := http.NewRequest(testcase.method, server.URL+testcase.path, strings.NewReader(testcase.body))
+		req.Header.Set("Grpc-Metadata-X-User", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if want, have := testcase.code, resp.StatusCode; want != have {
+			t.Errorf("%s %s: want %d, have %d (%s)", testcase.method, testcase.path, want, have, body)
+			continue
+		}
+		if testcase.response != "" && !jsonEqual(t, testcase.response, string(body)) {
+			t.Errorf("%s %s: want %s, have %s", testcase.method, testcase.path, testcase.response, body)
+		}
+		if testcase.code == 200 && testcase.method == "GET" {
+			if want, have := "kit", resp.Header.Get("Grpc-Metadata-X-Served-By"); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		}
+	}
+}
+
+func TestTranscoderHeaders(t *testing.T) {
+	var (
+		md     metadata.MD
+		server = kitgrpc.NewServer(endpointEcho,
+			func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+			func(_ context.Context, resp interface{}) (interface{}, error) { return resp, nil },
+			kitgrpc.ServerBefore(func(ctx context.Context, incoming metadata.MD) context.Context {
+				md = incoming
+				return ctx
+			}),
+		)
+		route = kitgrpc.Route{Method: http.MethodPost, Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook", Body: "*"}
+	)
+	for _, testcase := range []struct {
+		options []kitgrpc.TranscoderOption
+		want    metadata.MD
+	}{
+		{
+			nil,
+			metadata.MD{"authorization": {"Bearer token"}, "x-user": {"alice"}},
+		},
+		{
+			[]kitgrpc.TranscoderOption{kitgrpc.TranscoderHeaderMatcher(func(key string) (string, bool) {
+				return "http-" + strings.ToLower(key), key == "X-Forwarded-For"
+			})},
+			metadata.MD{"http-x-forwarded-for": {"10.0.0.1"}},
+		},
+	} {
+		transcoder, err := kitgrpc.NewTranscoder(map[string]kitgrpc.Handler{route.FullMethod: server},
+			append(testcase.options, kitgrpc.TranscoderRoutes(route))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/books", strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("Grpc-Metadata-X-User", "alice")
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		rec := httptest.NewRecorder()
+		transcoder.ServeHTTP(rec, req)
+		if want, have := http.StatusOK, rec.Code; want != have {
+			t.Fatalf("want %d, have %d (%s)", want, have, rec.Body)
+		}
+		if want, have := testcase.want, md; !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	}
+}
+
+func TestTranscoderMaxBodySize(t *testing.T) {
+	var (
+		server = kitgrpc.NewServer(endpointEcho,
+			func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+			func(_ context.Context, resp interface{}) (interface{}, error) { return resp, nil },
+		)
+		route = kitgrpc.Route{Method: http.MethodPost, Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook", Body: "*"}
+	)
+	transcoder, err := kitgrpc.NewTranscoder(map[string]kitgrpc.Handler{route.FullMethod: server},
+		kitgrpc.TranscoderRoutes(route),
+		kitgrpc.TranscoderMaxBodySize(16),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for body, want := range map[string]int{
+		`{"name":"short"}`:                              http.StatusOK,
+		`{"name":"far too long"}`:                       http.StatusBadRequest,
+		`{"name":"` + strings.Repeat("x", 1<<20) + `"}`: http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		transcoder.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/books", strings.NewReader(body)))
+		if have := rec.Code; want != have {
+			t.Errorf("%d bytes: want %d, have %d", len(body), want, have)
+		}
+	}
+}
+
+func TestTranscoderOverlappingRoutes(t *testing.T) {
+	var (
+		named = func(name string) kitgrpc.Handler {
+			return kitgrpc.NewServer(
+				func(context.Context, interface{}) (interface{}, error) {
+					return newBook(func(m protoreflect.Message, f protoreflect.FieldDescriptors) {
+						m.Set(f.ByName("name"), protoreflect.ValueOfString(name))
+					}), nil
+				},
+				func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+				func(_ context.Context, resp interface{}) (interface{}, error) { return resp, nil },
+			)
+		}
+		handlers = map[string]kitgrpc.Handler{
+			"/kittest.Library/GetBook":    named("get"),
+			"/kittest.Library/UpdateBook": named("update"),
+			"/kittest.Library/CreateBook": named("create"),
+		}
+		// The routes overlap, and the wildcard ones come first.
+		routes = kitgrpc.TranscoderRoutes(
+			kitgrpc.Route{Method: http.MethodGet, Path: "/v2/{name=**}", FullMethod: "/kittest.Library/UpdateBook"},
+			kitgrpc.Route{Method: http.MethodGet, Path: "/v2/books/{name}", FullMethod: "/kittest.Library/GetBook"},
+			kitgrpc.Route{Method: http.MethodGet, Path: "/v2/books/latest", FullMethod: "/kittest.Library/CreateBook"},
+		)
+	)
+	transcoder, err := kitgrpc.NewTranscoder(handlers, routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"/v2/books/latest": "create",
+		"/v2/books/1":      "get",
+		"/v2/shelves/1":    "update",
+		"/v2/books/1/x":    "update",
+	} {
+		rec := httptest.NewRecorder()
+		transcoder.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if !jsonEqual(t, `{"name":"`+want+`"}`, rec.Body.String()) {
+			t.Errorf("%s: want %s, have %s", path, want, rec.Body)
+		}
+	}
+}
+
+func TestTranscoderContextErrors(t *testing.T) {
+	route := kitgrpc.Route{Method: http.MethodPost, Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook", Body: "*"}
+	for err, want := range map[error]int{
+		context.Canceled:         499,
+		context.DeadlineExceeded: http.StatusGatewayTimeout,
+	} {
+		err := err
+		server := kitgrpc.NewServer(
+			func(context.Context, interface{}) (interface{}, error) { return nil, err },
+			func(_ context.Context, req interface{}) (interface{}, error) { return req, nil },
+			func(_ context.Context, resp interface{}) (interface{}, error) { return resp, nil },
+		)
+		transcoder, terr := kitgrpc.NewTranscoder(map[string]kitgrpc.Handler{route.FullMethod: server}, kitgrpc.TranscoderRoutes(route))
+		if terr != nil {
+			t.Fatal(terr)
+		}
+		rec := httptest.NewRecorder()
+		transcoder.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/books", strings.NewReader("{}")))
+		if have := rec.Code; want != have {
+			t.Errorf("%v: want %d, have %d", err, want, have)
+		}
+	}
+}
+
+func TestTranscoderErrors(t *testing.T) {
+	nop := kitgrpc.NewServer(endpointEcho, decodeString, encodeString)
+	for name, testcase := range map[string]struct {
+		handlers map[string]kitgrpc.Handler
+		routes   []kitgrpc.Route
+	}{
+		"unknown method":    {map[string]kitgrpc.Handler{"/kittest.Library/Nope": nop}, nil},
+		"no handler":        {nil, []kitgrpc.Route{{Method: "GET", Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook"}}},
+		"invalid template":  {map[string]kitgrpc.Handler{"/kittest.Library/CreateBook": nop}, []kitgrpc.Route{{Method: "GET", Path: "/v1/{name", FullMethod: "/kittest.Library/CreateBook"}}},
+		"unknown body":      {map[string]kitgrpc.Handler{"/kittest.Library/CreateBook": nop}, []kitgrpc.Route{{Method: "POST", Path: "/v1/books", FullMethod: "/kittest.Library/CreateBook", Body: "nope"}}},
+		"streaming method":  {map[string]kitgrpc.Handler{"/grpc.health.v1.Health/Watch": nop}, []kitgrpc.Route{{Method: "GET", Path: "/watch", FullMethod: "/grpc.health.v1.Health/Watch"}}},
+		"deep not at end":   {map[string]kitgrpc.Handler{"/kittest.Library/CreateBook": nop}, []kitgrpc.Route{{Method: "GET", Path: "/v1/{name=**}/books", FullMethod: "/kittest.Library/CreateBook"}}},
+		"no leading slash":  {map[string]kitgrpc.Handler{"/kittest.Library/CreateBook": nop}, []kitgrpc.Route{{Method: "GET", Path: "v1/books", FullMethod: "/kittest.Library/CreateBook"}}},
+		"invalid full name": {map[string]kitgrpc.Handler{"kittest.Library.CreateBook": nop}, nil},
+	} {
+		if _, err := kitgrpc.NewTranscoder(testcase.handlers, kitgrpc.TranscoderRoutes(testcase.routes...)); err == nil {
+			t.Errorf("%s: want error, have none", name)
+		}
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	for code, want := range map[codes.Code]int{
+		codes.OK:                http.StatusOK,
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.DataLoss:          http.StatusInternalServerError,
+	} {
+		if have := kitgrpc.HTTPStatusFromCode(code); want != have {
+			t.Errorf("%v: want %d, have %d", code, want, have)
+		}
+	}
+}
+
+func endpointEcho(_ context.Context, request interface{}) (interface{}, error) {
+	return request, nil
+}
+
+func jsonEqual(t *testing.T, a, b string) bool {
+	t.Helper()
+	var va, vb interface{}
+	if err := json.Unmarshal([]byte(a), &va); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(b), &vb); err != nil {
+		return false
+	}
+	return mustMarshal(va) == mustMarshal(vb)
+}
+
+func mustMarshal(v interface{}) string {
+	buf, _ := json.Marshal(v)
+	return string(buf)
+}
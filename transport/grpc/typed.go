@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// TypedDecodeRequestFunc is a type-safe variant of DecodeRequestFunc. GRPCReq
+// is the gRPC request message type, typically a pointer to a generated
+// protobuf struct.
+type TypedDecodeRequestFunc[GRPCReq, Req any] func(context.Context, GRPCReq) (request Req, err error)
+
+// Untyped converts the TypedDecodeRequestFunc to a DecodeRequestFunc.
+func (f TypedDecodeRequestFunc[GRPCReq, Req]) Untyped() DecodeRequestFunc {
+	return func(ctx context.Context, grpcReq interface{}) (interface{}, error) {
+		req, err := endpoint.Assert[GRPCReq](grpcReq)
+		if err != nil {
+			return nil, err
+		}
+		return f(ctx, req)
+	}
+}
+
+// TypedEncodeRequestFunc is a type-safe variant of EncodeRequestFunc.
+type TypedEncodeRequestFunc[Req, GRPCReq any] func(context.Context, Req) (request GRPCReq, err error)
+
+// Untyped converts the TypedEncodeRequestFunc to an EncodeRequestFunc.
+func (f TypedEncodeRequestFunc[Req, GRPCReq]) Untyped() EncodeRequestFunc {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req, err := endpoint.Assert[Req](request)
+		if err != nil {
+			return nil, err
+		}
+		return f(ctx, req)
+	}
+}
+
+// TypedEncodeResponseFunc is a type-safe variant of EncodeResponseFunc.
+type TypedEncodeResponseFunc[Resp, GRPCResp any] func(context.Context, Resp) (response GRPCResp, err error)
+
+// Untyped converts the TypedEncodeResponseFunc to an EncodeResponseFunc.
+func (f TypedEncodeResponseFunc[Resp, GRPCResp]) Untyped() EncodeResponseFunc {
+	return func(ctx context.Context, response interface{}) (interface{}, error) {
+		resp, err := endpoint.Assert[Resp](response)
+		if err != nil {
+			return nil, err
+		}
+		return f(ctx, resp)
+	}
+}
+
+// TypedDecodeResponseFunc is a type-safe variant of DecodeResponseFunc.
+type TypedDecodeResponseFunc[GRPCResp, Resp any] func(context.Context, GRPCResp) (response Resp, err error)
+
+// Untyped converts the TypedDecodeResponseFunc to a DecodeResponseFunc.
+func (f TypedDecodeResponseFunc[GRPCResp, Resp]) Untyped() DecodeResponseFunc {
+	return func(ctx context.Context, grpcResp interface{}) (interface{}, error) {
+		resp, err := endpoint.Assert[GRPCResp](grpcResp)
+		if err != nil {
+			return nil, err
+		}
+		return f(ctx, resp)
+	}
+}
+
+// NewTypedServer is like NewServer, but takes a Typed endpoint and type-safe
+// codecs. The returned Server is identical to one built with NewServer, and
+// accepts the same options.
+func NewTypedServer[GRPCReq, Req, Resp, GRPCResp any](
+	e endpoint.Typed[Req, Resp],
+	dec TypedDecodeRequestFunc[GRPCReq, Req],
+	enc TypedEncodeResponseFunc[Resp, GRPCResp],
+	options ...ServerOption,
+) *Server {
+	return NewServer(e.Endpoint(), dec.Untyped(), enc.Untyped(), options...)
+}
+
+// NewTypedClient is like NewClient, but takes type-safe codecs. The gRPC reply
+// type is derived from GRPCResp, which must be a pointer to the protobuf
+// message struct Reply, so no zero-value reply needs to be passed; Reply is
+// inferred from GRPCResp. Use endpoint.FromEndpoint to obtain a Typed endpoint
+// from the Client.
+func NewTypedClient[Req, GRPCReq any, GRPCResp interface{ *Reply }, Resp, Reply any](
+	cc *grpc.ClientConn,
+	serviceName string,
+	method string,
+	enc TypedEncodeRequestFunc[Req, GRPCReq],
+	dec TypedDecodeResponseFunc[GRPCResp, Resp],
+	options ...ClientOption,
+) *Client {
+	return NewClient(cc, serviceName, method, enc.Untyped(), dec.Untyped(), GRPCResp(new(Reply)), options...)
+}
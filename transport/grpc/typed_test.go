@@ -0,0 +1,47 @@
+package grpc_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+)
+
+func TestTypedServerAndClient(t *testing.T) {
+	server := kitgrpc.NewTypedServer(
+		endpoint.Typed[string, string](func(_ context.Context, request string) (string, error) {
+			return strings.ToUpper(request), nil
+		}),
+		func(_ context.Context, req *wrapperspb.StringValue) (string, error) { return req.GetValue(), nil },
+		func(_ context.Context, response string) (*wrapperspb.StringValue, error) {
+			return wrapperspb.String(response), nil
+		},
+	)
+	cc := startUnaryServer(t, server)
+
+	client := kitgrpc.NewTypedClient(
+		cc, "kit.test.Unary", "Unary",
+		func(_ context.Context, request string) (*wrapperspb.StringValue, error) {
+			return wrapperspb.String(request), nil
+		},
+		func(_ context.Context, reply *wrapperspb.StringValue) (string, error) { return reply.GetValue(), nil },
+	)
+
+	e := endpoint.FromEndpoint[string, string](client.Endpoint())
+	response, err := e(context.Background(), "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "HI", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	// The untyped endpoint rejects requests of the wrong type.
+	if _, err := client.Endpoint()(context.Background(), 42); err == nil {
+		t.Error("want error, have none")
+	}
+}
@@ -0,0 +1,182 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// ErrNoResponse is the error of a call of a batch that the server didn't
+// respond to.
+var ErrNoResponse = errors.New("jsonrpc: no response to call")
+
+// BatchClient sends several calls to a JSON RPC server in a single batch
+// request, and provides a method that implements endpoint.Endpoint.
+type BatchClient struct {
+	base Client
+}
+
+// Call is a call of a batch.
+type Call struct {
+	// Method is the JSON RPC method to call.
+	Method string
+
+	// Request is encoded into the params of the call.
+	Request interface{}
+
+	// Encode and Decode, if set, replace the request encoder and response
+	// decoder of the BatchClient for this call.
+	Encode EncodeRequestFunc
+	Decode DecodeResponseFunc
+
+	// Notification sends the call without an ID. The server doesn't respond
+	// to it, so its Result is empty, unless encoding it fails.
+	Notification bool
+}
+
+// Result is the outcome of a call of a batch.
+type Result struct {
+	Response interface{}
+	Err      error
+}
+
+// NewBatchClient constructs a usable BatchClient for the JSON RPC server at
+// the URL. It takes the same options as a Client; ClientRequestEncoder and
+// ClientResponseDecoder set the defaults for the calls of a batch, and the
+// other funcs apply to the batch as a whole.
+func NewBatchClient(tgt *url.URL, options ...ClientOption) *BatchClient {
+	return &BatchClient{base: *NewClient(tgt, "", options...)}
+}
+
+// Endpoint returns a usable endpoint that sends a batch of calls. Its request
+// must be a []Call, and its response is a []Result, in the same order. The
+// endpoint only returns an error if the batch as a whole fails; the errors of
+// individual calls, including those the server responds to with an error
+// object, are in their Result.
+func (c BatchClient) Endpoint() endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		calls, ok := request.([]Call)
+		if !ok {
+			return nil, errors.New("jsonrpc: batch request must be a []Call")
+		}
+
+		var (
+			resp *http.Response
+			err  error
+		)
+		if c.base.finalizer != nil {
+			defer func() {
+				if resp != nil {
+					ctx = context.WithValue(ctx, httptransport.ContextKeyResponseHeaders, resp.Header)
+					ctx = context.WithValue(ctx, httptransport.ContextKeyResponseSize, resp.ContentLength)
+				}
+				c.base.finalizer(ctx, err)
+			}()
+		}
+
+		var (
+			results = make([]Result, len(calls))
+			rpcReqs = make([]clientRequest, 0, len(calls))
+			pending = map[string]int{} // call index by JSON-encoded request ID
+		)
+		for i, call := range calls {
+			enc := call.Encode
+			if enc == nil {
+				enc = c.base.enc
+			}
+			params, encErr := enc(context.WithValue(ctx, ContextKeyRequestMethod, call.Method), call.Request)
+			if encErr != nil {
+				results[i].Err = encErr
+				continue
+			}
+			rpcReq := clientRequest{
+				JSONRPC: Version,
+				Method:  call.Method,
+				Params:  params,
+			}
+			if call.Notification {
+				rpcReqs = append(rpcReqs, rpcReq)
+				continue
+			}
+			rpcReq.ID = c.base.requestID.Generate()
+			var id []byte
+			if id, err = json.Marshal(rpcReq.ID); err != nil {
+				return nil, err
+			}
+			pending[string(id)] = i
+			rpcReqs = append(rpcReqs, rpcReq)
+		}
+		if len(rpcReqs) == 0 {
+			return results, nil
+		}
+
+		ctx, resp, err = c.base.post(ctx, rpcReqs)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		// Servers respond to batches they can't process at all with a single
+		// error response.
+		var rpcRess []Response
+		switch body = bytes.TrimSpace(body); {
+		case len(body) == 0:
+		case body[0] == '{':
+			var rpcRes Response
+			if err = json.Unmarshal(body, &rpcRes); err != nil {
+				return nil, err
+			}
+			if rpcRes.Error == nil {
+				err = errors.New("jsonrpc: unexpected response to batch")
+				return nil, err
+			}
+			err = *rpcRes.Error
+			return nil, err
+		default:
+			if err = json.Unmarshal(body, &rpcRess); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, rpcRes := range rpcRess {
+			if rpcRes.ID == nil {
+				continue
+			}
+			id, err := json.Marshal(rpcRes.ID)
+			if err != nil {
+				continue
+			}
+			i, ok := pending[string(id)]
+			if !ok {
+				continue
+			}
+			delete(pending, string(id))
+
+			dec := calls[i].Decode
+			if dec == nil {
+				dec = c.base.dec
+			}
+			results[i].Response, results[i].Err = dec(context.WithValue(ctx, ContextKeyRequestMethod, calls[i].Method), rpcRes)
+		}
+		for _, i := range pending {
+			results[i].Err = ErrNoResponse
+		}
+
+		return results, nil
+	}
+}
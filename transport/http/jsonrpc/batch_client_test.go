@@ -0,0 +1,123 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+func TestBatchClient(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	var (
+		target, _ = url.Parse(server.URL)
+		client    = jsonrpc.NewBatchClient(target)
+		encodeErr = errors.New("can't encode")
+		intResult = func(_ context.Context, res jsonrpc.Response) (interface{}, error) {
+			if res.Error != nil {
+				return nil, *res.Error
+			}
+			var n int
+			err := json.Unmarshal(res.Result, &n)
+			return n, err
+		}
+	)
+	response, err := client.Endpoint()(context.Background(), []jsonrpc.Call{
+		{Method: "add", Request: []int{3, 2}, Decode: intResult},
+		{Method: "fail"},
+		{Method: "add", Encode: func(context.Context, interface{}) (json.RawMessage, error) { return nil, encodeErr }},
+		{Method: "add", Request: []int{1, 1}, Decode: intResult},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := response.([]jsonrpc.Result)
+
+	if want, have := (jsonrpc.Result{Response: 5}), results[0]; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if rpcErr, ok := results[1].Err.(jsonrpc.Error); !ok || rpcErr.Code != jsonrpc.InternalError {
+		t.Errorf("want internal error, have %v", results[1].Err)
+	}
+	if want, have := encodeErr, results[2].Err; want != have {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := (jsonrpc.Result{Response: 2}), results[3]; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := int64(3), calls; want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestBatchClientErrors(t *testing.T) {
+	for name, testcase := range map[string]struct {
+		body string
+		want func(results []jsonrpc.Result, err error) bool
+	}{
+		"batch error": {
+			body: `{"jsonrpc": "2.0", "error": {"code": -32700, "message": "Parse error"}, "id": null}`,
+			want: func(_ []jsonrpc.Result, err error) bool {
+				rpcErr, ok := err.(jsonrpc.Error)
+				return ok && rpcErr.Code == jsonrpc.ParseError
+			},
+		},
+		"missing response": {
+			body: `[{"jsonrpc": "2.0", "result": 1, "id": 1}]`,
+			want: func(results []jsonrpc.Result, err error) bool {
+				return err == nil && results[0].Err == nil && results[1].Err == jsonrpc.ErrNoResponse
+			},
+		},
+		"invalid JSON": {
+			body: `[{`,
+			want: func(_ []jsonrpc.Result, err error) bool { return err != nil },
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(testcase.body))
+			}))
+			defer server.Close()
+
+			target, _ := url.Parse(server.URL)
+			client := jsonrpc.NewBatchClient(target, jsonrpc.ClientRequestIDGenerator(jsonrpc.NewAutoIncrementID(1)))
+			response, err := client.Endpoint()(context.Background(), []jsonrpc.Call{{Method: "a"}, {Method: "b"}})
+			results, _ := response.([]jsonrpc.Result)
+			if !testcase.want(results, err) {
+				t.Errorf("unexpected results %v, error %v", results, err)
+			}
+		})
+	}
+}
+
+func TestBatchClientFinalizerError(t *testing.T) {
+	var (
+		target, _ = url.Parse("http://localhost")
+		finalized error
+		client    = jsonrpc.NewBatchClient(target,
+			jsonrpc.ClientRequestIDGenerator(invalidID{}),
+			jsonrpc.ClientFinalizer(func(_ context.Context, err error) { finalized = err }),
+		)
+	)
+	_, err := client.Endpoint()(context.Background(), []jsonrpc.Call{{Method: "a"}})
+	if err == nil {
+		t.Fatal("want error, have none")
+	}
+	if want, have := err, finalized; want != have {
+		t.Errorf("finalizer: want %v, have %v", want, have)
+	}
+}
+
+// invalidID generates request IDs that can't be encoded.
+type invalidID struct{}
+
+func (invalidID) Generate() interface{} { return func() {} }
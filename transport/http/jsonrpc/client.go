@@ -30,13 +30,14 @@ type Client struct {
 	finalizer      httptransport.ClientFinalizerFunc
 	requestID      RequestIDGenerator
 	bufferedStream bool
+	notification   bool
 }
 
 type clientRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
-	ID      interface{}     `json:"id"`
+	ID      interface{}     `json:"id,omitempty"` // nil for notifications
 }
 
 // NewClient constructs a usable Client for a single remote method.
@@ -139,6 +140,14 @@ func BufferedStream(buffered bool) ClientOption {
 	return func(c *Client) { c.bufferedStream = buffered }
 }
 
+// ClientNotification sets whether the calls are sent as notifications, without
+// an ID. The server doesn't respond to notifications, so the endpoint returns
+// a nil response, without calling the response decoder, once the server
+// accepted the request.
+func ClientNotification(notification bool) ClientOption {
+	return func(c *Client) { c.notification = notification }
+}
+
 // Endpoint returns a usable endpoint that invokes the remote endpoint.
 func (c Client) Endpoint() endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
@@ -169,39 +178,25 @@ func (c Client) Endpoint() endpoint.Endpoint {
 			JSONRPC: Version,
 			Method:  c.method,
 			Params:  params,
-			ID:      c.requestID.Generate(),
 		}
-
-		req, err := http.NewRequest("POST", c.tgt.String(), nil)
-		if err != nil {
-			return nil, err
+		if !c.notification {
+			rpcReq.ID = c.requestID.Generate()
 		}
 
-		req.Header.Set("Content-Type", "application/json; charset=utf-8")
-		var b bytes.Buffer
-		req.Body = ioutil.NopCloser(&b)
-		err = json.NewEncoder(&b).Encode(rpcReq)
+		ctx, resp, err = c.post(ctx, rpcReq)
 		if err != nil {
 			return nil, err
 		}
 
-		for _, f := range c.before {
-			ctx = f(ctx, req)
-		}
-
-		resp, err = c.client.Do(req.WithContext(ctx))
-		if err != nil {
-			return nil, err
+		if c.notification {
+			resp.Body.Close()
+			return nil, nil
 		}
 
 		if !c.bufferedStream {
 			defer resp.Body.Close()
 		}
 
-		for _, f := range c.after {
-			ctx = f(ctx, resp)
-		}
-
 		// Decode the body into an object
 		var rpcRes Response
 		err = json.NewDecoder(resp.Body).Decode(&rpcRes)
@@ -218,6 +213,37 @@ func (c Client) Endpoint() endpoint.Endpoint {
 	}
 }
 
+// post sends the JSON RPC request or batch of requests, applying the
+// ClientBefore and ClientAfter funcs.
+func (c Client) post(ctx context.Context, body interface{}) (context.Context, *http.Response, error) {
+	req, err := http.NewRequest("POST", c.tgt.String(), nil)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	var b bytes.Buffer
+	req.Body = ioutil.NopCloser(&b)
+	err = json.NewEncoder(&b).Encode(body)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	for _, f := range c.before {
+		ctx = f(ctx, req)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	for _, f := range c.after {
+		ctx = f(ctx, resp)
+	}
+	return ctx, resp, nil
+}
+
 // ClientFinalizerFunc can be used to perform work at the end of a client HTTP
 // request, after the response is returned. The principal
 // intended use is for error logging. Additional response parameters are
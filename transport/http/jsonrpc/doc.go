@@ -1,3 +1,8 @@
 // Package jsonrpc provides a JSON RPC (v2.0) binding for endpoints.
 // See http://www.jsonrpc.org/specification
+//
+// Servers and Clients take the request and response funcs of package
+// transport/http. ExtractBaggage and InjectBaggage, and their response
+// counterparts, propagate baggage as HTTP headers, like the funcs of that
+// package they wrap.
 package jsonrpc
@@ -38,6 +38,11 @@ const (
 
 	// InternalError defines a server error
 	InternalError int = -32603
+
+	// ServerBusyError defines that the server is serving too many calls to
+	// serve this one, which may be retried later. It's in the range of
+	// implementation-defined server errors.
+	ServerBusyError int = -32000
 )
 
 var errorMessage = map[int]string{
@@ -46,6 +51,7 @@ var errorMessage = map[int]string{
 	MethodNotFoundError: "The method does not exist / is not available.",
 	InvalidParamsError:  "Invalid method parameter(s).",
 	InternalError:       "Internal JSON-RPC error.",
+	ServerBusyError:     "Server busy.",
 }
 
 // ErrorMessage returns a message for the JSON RPC error code. It returns the empty
@@ -98,3 +104,12 @@ func (e internalError) Error() string {
 func (e internalError) ErrorCode() int {
 	return InternalError
 }
+
+type serverBusyError string
+
+func (e serverBusyError) Error() string {
+	return string(e)
+}
+func (e serverBusyError) ErrorCode() int {
+	return ServerBusyError
+}
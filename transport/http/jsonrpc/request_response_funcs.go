@@ -0,0 +1,34 @@
+package jsonrpc
+
+import (
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// ExtractBaggage returns a RequestFunc for ServerBefore that copies the given
+// headers of the request, if present, into the baggage of the context. It's
+// the ExtractBaggage func of package transport/http.
+func ExtractBaggage(keys ...string) httptransport.RequestFunc {
+	return httptransport.ExtractBaggage(keys...)
+}
+
+// InjectBaggage returns a RequestFunc for ClientBefore, or
+// WebSocketClientBefore, that sets the given keys of the baggage of the
+// context, if present, as headers of the request. It's the InjectBaggage func
+// of package transport/http.
+func InjectBaggage(keys ...string) httptransport.RequestFunc {
+	return httptransport.InjectBaggage(keys...)
+}
+
+// InjectResponseBaggage returns a ServerResponseFunc for ServerAfter that sets
+// the given keys of the baggage of the context, if present, as headers of the
+// response.
+func InjectResponseBaggage(keys ...string) httptransport.ServerResponseFunc {
+	return httptransport.InjectResponseBaggage(keys...)
+}
+
+// ExtractResponseBaggage returns a ClientResponseFunc for ClientAfter that
+// copies the given headers of the response, if present, into the baggage of
+// the context.
+func ExtractResponseBaggage(keys ...string) httptransport.ClientResponseFunc {
+	return httptransport.ExtractResponseBaggage(keys...)
+}
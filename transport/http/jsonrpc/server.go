@@ -1,11 +1,13 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 
 	httptransport "github.com/go-kit/kit/transport/http"
 	"github.com/go-kit/log"
@@ -24,6 +26,8 @@ type Server struct {
 	errorEncoder httptransport.ErrorEncoder
 	finalizer    httptransport.ServerFinalizerFunc
 	logger       log.Logger
+
+	batchConcurrency int
 }
 
 // NewServer constructs a new server, which implements http.Server.
@@ -35,6 +39,8 @@ func NewServer(
 		ecm:          ecm,
 		errorEncoder: DefaultErrorEncoder,
 		logger:       log.NewNopLogger(),
+
+		batchConcurrency: 10,
 	}
 	for _, option := range options {
 		option(s)
@@ -88,7 +94,19 @@ func ServerFinalizer(f httptransport.ServerFinalizerFunc) ServerOption {
 	return func(s *Server) { s.finalizer = f }
 }
 
-// ServeHTTP implements http.Handler.
+// ServerBatchConcurrency sets the maximum number of calls of a batch request
+// that are served concurrently. By default, up to 10 calls are.
+func ServerBatchConcurrency(n int) ServerOption {
+	return func(s *Server) {
+		if n > 0 {
+			s.batchConcurrency = n
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler. The request may be a single call, or a
+// batch of them. Notifications, calls without an ID, are responded to with
+// 204 No Content.
 func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
@@ -108,9 +126,9 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		ctx = f(ctx, r)
 	}
 
-	// Decode the body into an  object
-	var req Request
-	err := json.NewDecoder(r.Body).Decode(&req)
+	// Decode the body into an  object, or an array of them
+	var raw json.RawMessage
+	err := json.NewDecoder(r.Body).Decode(&raw)
 	if err != nil {
 		rpcerr := parseError("JSON could not be decoded: " + err.Error())
 		s.logger.Log("err", rpcerr)
@@ -118,6 +136,33 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isBatch(raw) {
+		s.serveBatch(ctx, w, r, raw)
+		return
+	}
+
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		rpcerr := parseError("JSON could not be decoded: " + err.Error())
+		s.logger.Log("err", rpcerr)
+		s.errorEncoder(ctx, rpcerr, w)
+		return
+	}
+
+	// Notifications are served, but not responded to, even if they fail.
+	if isNotification(raw) {
+		bw := newBatchWriter()
+		s.serve(ctx, bw, r, req)
+		mergeHeader(w.Header(), bw.header)
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.serve(ctx, w, r, req)
+}
+
+// serve handles a single call.
+func (s Server) serve(ctx context.Context, w http.ResponseWriter, r *http.Request, req Request) {
 	ctx = context.WithValue(ctx, requestIDKey, req.ID)
 	ctx = context.WithValue(ctx, ContextKeyRequestMethod, req.Method)
 
@@ -174,6 +219,123 @@ func (s Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(res)
 }
 
+// serveBatch handles a batch of calls. Each call is served to its own buffer,
+// so the error encoder and the ServerAfter funcs apply to calls as usual; the
+// headers they set are merged into the response, with the values of all the
+// calls, and the bodies collected into the response array. Calls without an
+// ID are notifications, which are served, but not responded to.
+func (s Server) serveBatch(ctx context.Context, w http.ResponseWriter, r *http.Request, raw json.RawMessage) {
+	var calls []json.RawMessage
+	if err := json.Unmarshal(raw, &calls); err != nil {
+		rpcerr := parseError("JSON could not be decoded: " + err.Error())
+		s.logger.Log("err", rpcerr)
+		s.errorEncoder(ctx, rpcerr, w)
+		return
+	}
+	if len(calls) == 0 {
+		err := invalidRequestError("Batch is empty.")
+		s.logger.Log("err", err)
+		s.errorEncoder(ctx, err, w)
+		return
+	}
+
+	var (
+		buffers = make([]*batchWriter, len(calls))
+		sem     = make(chan struct{}, s.batchConcurrency)
+		wg      sync.WaitGroup
+	)
+	for i, call := range calls {
+		var req Request
+		if err := json.Unmarshal(call, &req); err != nil {
+			// The request ID isn't known, so it's responded to with null.
+			bw := newBatchWriter()
+			err := invalidRequestError("Request could not be decoded: " + err.Error())
+			s.logger.Log("err", err)
+			s.errorEncoder(ctx, err, bw)
+			buffers[i] = bw
+			continue
+		}
+		bw := newBatchWriter()
+		if !isNotification(call) {
+			buffers[i] = bw
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			s.serve(ctx, bw, r, req)
+		}()
+	}
+	wg.Wait()
+
+	var responses []json.RawMessage
+	for _, bw := range buffers {
+		if bw == nil {
+			continue
+		}
+		mergeHeader(w.Header(), bw.header)
+		if body := bytes.TrimSpace(bw.buf.Bytes()); len(body) > 0 {
+			responses = append(responses, body)
+		}
+	}
+
+	// Batches of notifications are responded to with nothing at all.
+	if len(responses) == 0 {
+		w.Header().Del("Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+// mergeHeader adds the values of the header src to dst, so that the headers
+// set by several calls are all kept. Values already present aren't repeated,
+// so headers set to the same value by every call appear once.
+func mergeHeader(dst, src http.Header) {
+	for k, values := range src {
+	next:
+		for _, v := range values {
+			for _, have := range dst[k] {
+				if have == v {
+					continue next
+				}
+			}
+			dst[k] = append(dst[k], v)
+		}
+	}
+}
+
+// isBatch reports whether the request body is an array of calls.
+func isBatch(raw json.RawMessage) bool {
+	raw = bytes.TrimLeft(raw, " \t\r\n")
+	return len(raw) > 0 && raw[0] == '['
+}
+
+// isNotification reports whether the call has no ID member. Calls with a null
+// ID aren't notifications, even if they decode to a nil ID.
+func isNotification(call json.RawMessage) bool {
+	var members struct {
+		ID json.RawMessage `json:"id"`
+	}
+	return json.Unmarshal(call, &members) == nil && members.ID == nil
+}
+
+// batchWriter buffers the response to a call of a batch.
+type batchWriter struct {
+	header http.Header
+	buf    bytes.Buffer
+}
+
+func newBatchWriter() *batchWriter {
+	return &batchWriter{header: http.Header{}}
+}
+
+func (w *batchWriter) Header() http.Header         { return w.header }
+func (w *batchWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
+func (w *batchWriter) WriteHeader(int)             {}
+
 // DefaultErrorEncoder writes the error to the ResponseWriter,
 // as a json-rpc error response, with an InternalError status code.
 // The Error() string of the error will be used as the response error message.
@@ -4,15 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport/baggage"
 	"github.com/go-kit/kit/transport/http/jsonrpc"
 )
 
@@ -405,3 +411,253 @@ func testServer(t *testing.T) (step func(), resp <-chan *http.Response) {
 	}()
 	return func() { stepch <- true }, response
 }
+
+func TestServerBaggage(t *testing.T) {
+	ecm := jsonrpc.EndpointCodecMap{
+		"tenant": jsonrpc.EndpointCodec{
+			Endpoint: func(ctx context.Context, _ interface{}) (interface{}, error) {
+				tenant, _ := baggage.Get(ctx, "X-Tenant-ID")
+				return tenant, nil
+			},
+			Decode: nopDecoder,
+			Encode: func(_ context.Context, response interface{}) (json.RawMessage, error) {
+				return json.Marshal(response)
+			},
+		},
+	}
+	handler := jsonrpc.NewServer(ecm,
+		jsonrpc.ServerBefore(jsonrpc.ExtractBaggage("X-Tenant-ID")),
+		jsonrpc.ServerAfter(jsonrpc.InjectResponseBaggage("X-Tenant-ID")),
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := jsonrpc.NewClient(target, "tenant",
+		jsonrpc.ClientBefore(jsonrpc.InjectBaggage("X-Tenant-ID")),
+		jsonrpc.ClientAfter(jsonrpc.ExtractResponseBaggage("X-Tenant-ID")),
+		jsonrpc.ClientResponseDecoder(func(ctx context.Context, res jsonrpc.Response) (interface{}, error) {
+			var tenant string
+			if err := json.Unmarshal(res.Result, &tenant); err != nil {
+				return nil, err
+			}
+			echoed, _ := baggage.Get(ctx, "X-Tenant-ID")
+			return tenant + " " + echoed, nil
+		}),
+	)
+
+	ctx := baggage.Set(context.Background(), "X-Tenant-ID", "acme")
+	response, err := client.Endpoint()(ctx, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme acme", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+// addECM returns an EndpointCodecMap with an "add" method summing its params,
+// and a "fail" method always failing. Calls to either are counted.
+func addECM(calls *int64) jsonrpc.EndpointCodecMap {
+	return jsonrpc.EndpointCodecMap{
+		"add": jsonrpc.EndpointCodec{
+			Endpoint: func(_ context.Context, request interface{}) (interface{}, error) {
+				atomic.AddInt64(calls, 1)
+				var sum int
+				for _, n := range request.([]int) {
+					sum += n
+				}
+				return sum, nil
+			},
+			Decode: func(_ context.Context, params json.RawMessage) (interface{}, error) {
+				var ns []int
+				err := json.Unmarshal(params, &ns)
+				return ns, err
+			},
+			Encode: func(_ context.Context, response interface{}) (json.RawMessage, error) {
+				return json.Marshal(response)
+			},
+		},
+		"fail": jsonrpc.EndpointCodec{
+			Endpoint: func(context.Context, interface{}) (interface{}, error) {
+				atomic.AddInt64(calls, 1)
+				return nil, errors.New("dang")
+			},
+			Decode: nopDecoder,
+			Encode: nopEncoder,
+		},
+	}
+}
+
+func TestServerBatch(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", body(` [
+		{"jsonrpc": "2.0", "method": "add", "params": [3, 2], "id": 1},
+		{"jsonrpc": "2.0", "method": "add", "params": [1, 1]},
+		{"jsonrpc": "2.0", "method": "fail", "id": "x"},
+		1,
+		{"jsonrpc": "2.0", "method": "nope", "id": 2}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var responses []jsonrpc.Response
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 4, len(responses); want != have {
+		t.Fatalf("want %d responses, have %d", want, have)
+	}
+	if want, have := `5`, string(responses[0].Result); want != have {
+		t.Errorf("want %s, have %s", want, have)
+	}
+	for i, want := range []int{0, jsonrpc.InternalError, jsonrpc.InvalidRequestError, jsonrpc.MethodNotFoundError} {
+		var have int
+		if responses[i].Error != nil {
+			have = responses[i].Error.Code
+		}
+		if want != have {
+			t.Errorf("response %d: want error code %d, have %d", i, want, have)
+		}
+	}
+	if id, _ := responses[1].ID.String(); id != "x" {
+		t.Errorf("want ID x, have %q", id)
+	}
+	if responses[2].ID != nil {
+		t.Errorf("want null ID, have %v", responses[2].ID)
+	}
+
+	// The notification is served, but not responded to.
+	if want, have := int64(3), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestServerBatchNotifications(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", body(`[
+		{"jsonrpc": "2.0", "method": "add", "params": [1, 1]},
+		{"jsonrpc": "2.0", "method": "fail"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf, _ := ioutil.ReadAll(resp.Body)
+
+	if want, have := http.StatusNoContent, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if len(buf) > 0 {
+		t.Errorf("want no body, have %s", buf)
+	}
+	if want, have := int64(2), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestServerBatchHeaders(t *testing.T) {
+	var (
+		calls int64
+		n     int64
+		after = func(ctx context.Context, w http.ResponseWriter) context.Context {
+			w.Header().Add("X-Call", fmt.Sprint(atomic.AddInt64(&n, 1)))
+			w.Header().Set("X-Server", "kit")
+			return ctx
+		}
+		server = httptest.NewServer(jsonrpc.NewServer(addECM(&calls), jsonrpc.ServerAfter(after)))
+	)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", body(`[
+		{"jsonrpc": "2.0", "method": "add", "params": [1, 1], "id": 1},
+		{"jsonrpc": "2.0", "method": "add", "params": [2, 2], "id": 2}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// The headers of all the calls are kept, and identical values are only
+	// sent once.
+	have := resp.Header.Values("X-Call")
+	sort.Strings(have)
+	if want := []string{"1", "2"}; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"kit"}, resp.Header.Values("X-Server"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+	if want, have := []string{jsonrpc.ContentType}, resp.Header.Values("Content-Type"); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
+
+func TestServerBatchEmpty(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", body(`[]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf, _ := ioutil.ReadAll(resp.Body)
+	expectErrorCode(t, jsonrpc.InvalidRequestError, buf)
+}
+
+func TestServerBatchConcurrency(t *testing.T) {
+	var (
+		inflight, max int64
+		ecm           = jsonrpc.EndpointCodecMap{
+			"wait": jsonrpc.EndpointCodec{
+				Endpoint: func(context.Context, interface{}) (interface{}, error) {
+					n := atomic.AddInt64(&inflight, 1)
+					defer atomic.AddInt64(&inflight, -1)
+					for {
+						m := atomic.LoadInt64(&max)
+						if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					return struct{}{}, nil
+				},
+				Decode: nopDecoder,
+				Encode: nopEncoder,
+			},
+		}
+		server = httptest.NewServer(jsonrpc.NewServer(ecm, jsonrpc.ServerBatchConcurrency(2)))
+	)
+	defer server.Close()
+
+	var calls []string
+	for i := 0; i < 6; i++ {
+		calls = append(calls, fmt.Sprintf(`{"jsonrpc": "2.0", "method": "wait", "id": %d}`, i))
+	}
+	resp, err := http.Post(server.URL, "application/json", body("["+strings.Join(calls, ",")+"]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var responses []jsonrpc.Response
+	if err := json.NewDecoder(resp.Body).Decode(&responses); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 6, len(responses); want != have {
+		t.Errorf("want %d responses, have %d", want, have)
+	}
+	if want, have := int64(2), atomic.LoadInt64(&max); want != have {
+		t.Errorf("want %d concurrent calls, have %d", want, have)
+	}
+}
@@ -0,0 +1,479 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// ErrWebSocketClosed is returned by calls over a WebSocketConn that was
+// closed before they got a response.
+var ErrWebSocketClosed = errors.New("jsonrpc: websocket connection closed")
+
+const (
+	defaultWebSocketConcurrency = 10
+	defaultWebSocketReadLimit   = 1 << 20
+)
+
+// WebSocketConn carries JSON RPC over a WebSocket connection in both
+// directions. It serves the calls of the peer with a Server, and provides
+// endpoints calling the methods of the peer.
+type WebSocketConn struct {
+	ws        *websocket.Conn
+	server    Server
+	r         *http.Request // the handshake request, given to RequestFuncs
+	requestID RequestIDGenerator
+	sem       chan struct{} // bounds the calls of the peer served concurrently
+
+	writeMtx sync.Mutex
+
+	mtx     sync.Mutex
+	pending map[string]chan Response // by JSON-encoded request ID
+	done    chan struct{}
+}
+
+func newWebSocketConn(ws *websocket.Conn, server *Server, r *http.Request, concurrency int, readLimit int64) *WebSocketConn {
+	if server == nil {
+		server = NewServer(EndpointCodecMap{})
+	}
+	ws.SetReadLimit(readLimit)
+	return &WebSocketConn{
+		ws:        ws,
+		server:    *server,
+		r:         r,
+		requestID: NewAutoIncrementID(0),
+		sem:       make(chan struct{}, concurrency),
+		pending:   map[string]chan Response{},
+		done:      make(chan struct{}),
+	}
+}
+
+// Endpoint returns a usable endpoint that calls the method of the peer. The
+// request is encoded with enc, and the response decoded with dec; nil selects
+// DefaultRequestEncoder and DefaultResponseDecoder respectively.
+func (c *WebSocketConn) Endpoint(method string, enc EncodeRequestFunc, dec DecodeResponseFunc) endpoint.Endpoint {
+	if enc == nil {
+		enc = DefaultRequestEncoder
+	}
+	if dec == nil {
+		dec = DefaultResponseDecoder
+	}
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		ctx = context.WithValue(ctx, ContextKeyRequestMethod, method)
+
+		params, err := enc(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		rpcReq := clientRequest{
+			JSONRPC: Version,
+			Method:  method,
+			Params:  params,
+			ID:      c.requestID.Generate(),
+		}
+		id, err := json.Marshal(rpcReq.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		responses := make(chan Response, 1)
+		c.mtx.Lock()
+		c.pending[string(id)] = responses
+		c.mtx.Unlock()
+		defer func() {
+			c.mtx.Lock()
+			delete(c.pending, string(id))
+			c.mtx.Unlock()
+		}()
+
+		if err := c.write(rpcReq); err != nil {
+			return nil, err
+		}
+
+		select {
+		case rpcRes := <-responses:
+			return dec(ctx, rpcRes)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-c.done:
+			return nil, ErrWebSocketClosed
+		}
+	}
+}
+
+// Notify sends a notification to the peer, with the JSON encoding of params.
+func (c *WebSocketConn) Notify(ctx context.Context, method string, params interface{}) error {
+	buf, err := DefaultRequestEncoder(ctx, params)
+	if err != nil {
+		return err
+	}
+	return c.write(clientRequest{
+		JSONRPC: Version,
+		Method:  method,
+		Params:  buf,
+	})
+}
+
+// Done returns a channel that's closed when the connection is closed, by
+// either peer.
+func (c *WebSocketConn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close closes the connection. Calls waiting for a response fail with
+// ErrWebSocketClosed.
+func (c *WebSocketConn) Close() error {
+	c.writeMtx.Lock()
+	_ = c.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMtx.Unlock()
+	return c.ws.Close()
+}
+
+func (c *WebSocketConn) write(v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	select {
+	case <-c.done:
+		return ErrWebSocketClosed
+	default:
+	}
+	return c.ws.WriteMessage(websocket.TextMessage, buf)
+}
+
+// run reads messages until the connection is closed. Responses are handed to
+// the calls waiting for them, and calls of the peer are served concurrently,
+// so that they may call the peer in turn. Once as many calls as allowed are
+// being served, further calls are rejected, so that run keeps reading the
+// responses the calls being served may wait for.
+func (c *WebSocketConn) run(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer close(c.done)
+
+	for {
+		_, msg, err := c.ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.server.logger.Log("err", err)
+			}
+			c.ws.Close()
+			return
+		}
+
+		if rpcRes, ok := isResponse(msg); ok {
+			id, _ := json.Marshal(rpcRes.ID)
+			c.mtx.Lock()
+			responses, ok := c.pending[string(id)]
+			c.mtx.Unlock()
+			if ok {
+				select {
+				case responses <- rpcRes:
+				default: // a duplicate response, or a late one for a call that gave up
+				}
+			}
+			continue
+		}
+
+		select {
+		case c.sem <- struct{}{}:
+			go func() {
+				defer func() { <-c.sem }()
+				c.serve(ctx, msg)
+			}()
+		default:
+			c.reject(ctx, msg)
+		}
+	}
+}
+
+// serve handles a call, or a batch of calls, of the peer, the way Server does
+// over HTTP.
+func (c *WebSocketConn) serve(ctx context.Context, msg json.RawMessage) {
+	bw := newBatchWriter()
+	switch {
+	case isBatch(msg):
+		c.server.serveBatch(ctx, bw, c.r, msg)
+	default:
+		var req Request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			rpcerr := parseError("JSON could not be decoded: " + err.Error())
+			c.server.logger.Log("err", rpcerr)
+			c.server.errorEncoder(ctx, rpcerr, bw)
+			break
+		}
+		c.server.serve(ctx, bw, c.r, req)
+		if isNotification(msg) {
+			return
+		}
+	}
+
+	c.writeMtx.Lock()
+	defer c.writeMtx.Unlock()
+	if body := bw.buf.Bytes(); len(body) > 0 {
+		if err := c.ws.WriteMessage(websocket.TextMessage, body); err != nil {
+			c.server.logger.Log("err", err)
+		}
+	}
+}
+
+// reject responds to the calls of the message, a single call or a batch, with
+// a ServerBusyError, without serving them. Notifications are dropped.
+func (c *WebSocketConn) reject(ctx context.Context, msg json.RawMessage) {
+	err := serverBusyError("Too many calls are being served.")
+	c.server.logger.Log("err", err)
+
+	calls := []json.RawMessage{msg}
+	if isBatch(msg) && json.Unmarshal(msg, &calls) != nil {
+		return
+	}
+	var responses []json.RawMessage
+	for _, call := range calls {
+		var req Request
+		if json.Unmarshal(call, &req) != nil || isNotification(call) {
+			continue
+		}
+		bw := newBatchWriter()
+		c.server.errorEncoder(context.WithValue(ctx, requestIDKey, req.ID), err, bw)
+		if body := bytes.TrimSpace(bw.buf.Bytes()); len(body) > 0 {
+			responses = append(responses, body)
+		}
+	}
+
+	var write error
+	switch {
+	case len(responses) == 0:
+		return
+	case isBatch(msg):
+		write = c.write(responses)
+	default:
+		write = c.write(responses[0])
+	}
+	if write != nil {
+		c.server.logger.Log("err", write)
+	}
+}
+
+// isResponse reports whether the message is a response to a call, rather
+// than a call of the peer.
+func isResponse(msg json.RawMessage) (Response, bool) {
+	var members struct {
+		Method json.RawMessage `json:"method"`
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if isBatch(msg) || json.Unmarshal(msg, &members) != nil || members.Method != nil {
+		return Response{}, false
+	}
+	if members.Result == nil && members.Error == nil {
+		return Response{}, false
+	}
+	var rpcRes Response
+	if json.Unmarshal(msg, &rpcRes) != nil || rpcRes.ID == nil {
+		return Response{}, false
+	}
+	return rpcRes, true
+}
+
+// WebSocketServer is an http.Handler that upgrades requests to WebSocket
+// connections, over which it serves the calls of the clients with a Server.
+// Services can call their clients back over the connections, which are handed
+// to the OnConnect func.
+type WebSocketServer struct {
+	server      *Server
+	upgrader    websocket.Upgrader
+	onConnect   func(context.Context, *WebSocketConn)
+	concurrency int
+	readLimit   int64
+}
+
+// NewWebSocketServer returns a WebSocketServer serving calls with the Server,
+// and its EndpointCodecMap and options. ServerBefore funcs are applied to the
+// handshake request, and the resulting context is the parent of the context
+// of every call over the connection; ServerBeforeCodec funcs are applied to
+// every call, with the handshake request. ServerAfter funcs can't set headers
+// over a WebSocket connection. The ServerFinalizer is called when the
+// connection closes. The Server must not be nil.
+func NewWebSocketServer(server *Server, options ...WebSocketServerOption) *WebSocketServer {
+	if server == nil {
+		panic("nil Server")
+	}
+	s := &WebSocketServer{
+		server:      server,
+		concurrency: defaultWebSocketConcurrency,
+		readLimit:   defaultWebSocketReadLimit,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// WebSocketServerOption sets an optional parameter for WebSocket servers.
+type WebSocketServerOption func(*WebSocketServer)
+
+// WebSocketUpgrader sets the Upgrader used to upgrade the requests, e.g. to
+// check their origin. By default, the zero Upgrader is used.
+func WebSocketUpgrader(upgrader websocket.Upgrader) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.upgrader = upgrader }
+}
+
+// WebSocketOnConnect sets a func called with every new connection, while it's
+// open. Its context is canceled when the connection closes.
+func WebSocketOnConnect(f func(context.Context, *WebSocketConn)) WebSocketServerOption {
+	return func(s *WebSocketServer) { s.onConnect = f }
+}
+
+// WebSocketConcurrency sets the maximum number of calls of a client that are
+// served concurrently over its connection. Further calls are responded to with
+// a ServerBusyError until one of them completes, and notifications are
+// dropped. By default, up to 10 calls are.
+func WebSocketConcurrency(n int) WebSocketServerOption {
+	return func(s *WebSocketServer) {
+		if n > 0 {
+			s.concurrency = n
+		}
+	}
+}
+
+// WebSocketReadLimit sets the maximum size of the messages read from clients,
+// in bytes. Connections receiving larger messages are closed. By default,
+// messages are limited to 1 MiB.
+func WebSocketReadLimit(n int64) WebSocketServerOption {
+	return func(s *WebSocketServer) {
+		if n > 0 {
+			s.readLimit = n
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	for _, f := range s.server.before {
+		ctx = f(ctx, r)
+	}
+
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.server.logger.Log("err", err)
+		return // the upgrader responded with an HTTP error
+	}
+	if s.server.finalizer != nil {
+		defer s.server.finalizer(ctx, http.StatusSwitchingProtocols, r)
+	}
+
+	conn := newWebSocketConn(ws, s.server, r, s.concurrency, s.readLimit)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		conn.run(ctx)
+		cancel()
+	}()
+
+	if s.onConnect != nil {
+		s.onConnect(ctx, conn)
+	}
+	<-conn.Done()
+}
+
+// WebSocketClient connects to a WebSocketServer.
+type WebSocketClient struct {
+	dialer      *websocket.Dialer
+	tgt         *url.URL
+	server      *Server
+	before      []httptransport.RequestFunc
+	concurrency int
+	readLimit   int64
+}
+
+// NewWebSocketClient constructs a usable WebSocketClient for the server at
+// the URL, with a ws or wss scheme.
+func NewWebSocketClient(tgt *url.URL, options ...WebSocketClientOption) *WebSocketClient {
+	c := &WebSocketClient{
+		dialer:      websocket.DefaultDialer,
+		tgt:         tgt,
+		concurrency: defaultWebSocketConcurrency,
+		readLimit:   defaultWebSocketReadLimit,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WebSocketClientOption sets an optional parameter for WebSocket clients.
+type WebSocketClientOption func(*WebSocketClient)
+
+// WebSocketDialer sets the Dialer used to connect to the server. By default,
+// websocket.DefaultDialer is used.
+func WebSocketDialer(dialer *websocket.Dialer) WebSocketClientOption {
+	return func(c *WebSocketClient) { c.dialer = dialer }
+}
+
+// WebSocketClientBefore sets the RequestFuncs that are applied to the
+// handshake request, e.g. to set its headers.
+func WebSocketClientBefore(before ...httptransport.RequestFunc) WebSocketClientOption {
+	return func(c *WebSocketClient) { c.before = append(c.before, before...) }
+}
+
+// WebSocketClientServer sets the Server serving the calls of the server over
+// the connection. By default, the client serves no methods.
+func WebSocketClientServer(server *Server) WebSocketClientOption {
+	return func(c *WebSocketClient) { c.server = server }
+}
+
+// WebSocketClientConcurrency sets the maximum number of calls of the server
+// that are served concurrently, like WebSocketConcurrency does for servers. By
+// default, up to 10 calls are.
+func WebSocketClientConcurrency(n int) WebSocketClientOption {
+	return func(c *WebSocketClient) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// WebSocketClientReadLimit sets the maximum size of the messages read from
+// the server, in bytes, like WebSocketReadLimit does for servers. By default,
+// messages are limited to 1 MiB.
+func WebSocketClientReadLimit(n int64) WebSocketClientOption {
+	return func(c *WebSocketClient) {
+		if n > 0 {
+			c.readLimit = n
+		}
+	}
+}
+
+// Dial connects to the server. The context only bounds the handshake; the
+// connection stays open until either peer closes it.
+func (c WebSocketClient) Dial(ctx context.Context) (*WebSocketConn, error) {
+	r, err := http.NewRequest("GET", c.tgt.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range c.before {
+		ctx = f(ctx, r)
+	}
+
+	ws, _, err := c.dialer.DialContext(ctx, c.tgt.String(), r.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newWebSocketConn(ws, c.server, r, c.concurrency, c.readLimit)
+	go conn.run(context.Background())
+	return conn, nil
+}
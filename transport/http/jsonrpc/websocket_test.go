@@ -0,0 +1,307 @@
+package jsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/transport/baggage"
+	"github.com/go-kit/kit/transport/http/jsonrpc"
+)
+
+func TestServerNotification(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/json", body(`{"jsonrpc": "2.0", "method": "fail"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf, _ := ioutil.ReadAll(resp.Body)
+
+	if want, have := http.StatusNoContent, resp.StatusCode; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+	if len(buf) > 0 {
+		t.Errorf("want no body, have %s", buf)
+	}
+	if want, have := int64(1), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestClientNotification(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewServer(addECM(&calls)))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	client := jsonrpc.NewClient(target, "add",
+		jsonrpc.ClientNotification(true),
+		jsonrpc.ClientResponseDecoder(func(context.Context, jsonrpc.Response) (interface{}, error) {
+			return nil, errors.New("decoded a notification")
+		}),
+	)
+	response, err := client.Endpoint()(context.Background(), []int{3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != nil {
+		t.Errorf("want nil response, have %v", response)
+	}
+	if want, have := int64(1), atomic.LoadInt64(&calls); want != have {
+		t.Errorf("want %d calls, have %d", want, have)
+	}
+}
+
+func decodeInt(_ context.Context, res jsonrpc.Response) (interface{}, error) {
+	if res.Error != nil {
+		return nil, *res.Error
+	}
+	var n int
+	err := json.Unmarshal(res.Result, &n)
+	return n, err
+}
+
+func wsURL(server *httptest.Server) *url.URL {
+	target, _ := url.Parse("ws" + strings.TrimPrefix(server.URL, "http"))
+	return target
+}
+
+func TestWebSocket(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewWebSocketServer(jsonrpc.NewServer(addECM(&calls))))
+	defer server.Close()
+
+	conn, err := jsonrpc.NewWebSocketClient(wsURL(server)).Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	add := conn.Endpoint("add", nil, decodeInt)
+	response, err := add(context.Background(), []int{3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 5, response; want != have {
+		t.Errorf("want %d, have %v", want, have)
+	}
+
+	_, err = conn.Endpoint("nope", nil, decodeInt)(context.Background(), struct{}{})
+	var rpcerr jsonrpc.Error
+	if !errors.As(err, &rpcerr) || rpcerr.Code != jsonrpc.MethodNotFoundError {
+		t.Errorf("want method not found error, have %v", err)
+	}
+
+	if err := conn.Notify(context.Background(), "add", []int{1, 1}); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); atomic.LoadInt64(&calls) < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("notification wasn't served")
+		}
+	}
+
+	conn.Close()
+	if _, err := add(context.Background(), []int{3, 2}); err != jsonrpc.ErrWebSocketClosed {
+		t.Errorf("want %v, have %v", jsonrpc.ErrWebSocketClosed, err)
+	}
+}
+
+func TestWebSocketServerCalls(t *testing.T) {
+	var (
+		calls   int64
+		results = make(chan interface{}, 1)
+		handler = jsonrpc.NewWebSocketServer(
+			jsonrpc.NewServer(jsonrpc.EndpointCodecMap{}),
+			jsonrpc.WebSocketOnConnect(func(ctx context.Context, conn *jsonrpc.WebSocketConn) {
+				response, err := conn.Endpoint("add", nil, decodeInt)(ctx, []int{4, 4})
+				if err != nil {
+					results <- err
+					return
+				}
+				results <- response
+			}),
+		)
+		server = httptest.NewServer(handler)
+	)
+	defer server.Close()
+
+	client := jsonrpc.NewWebSocketClient(wsURL(server),
+		jsonrpc.WebSocketClientServer(jsonrpc.NewServer(addECM(&calls))),
+	)
+	conn, err := client.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	select {
+	case result := <-results:
+		if want, have := 8, result; want != have {
+			t.Errorf("want %d, have %v", want, have)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server didn't call the client")
+	}
+}
+
+func TestWebSocketBaggage(t *testing.T) {
+	ecm := jsonrpc.EndpointCodecMap{
+		"tenant": jsonrpc.EndpointCodec{
+			Endpoint: func(ctx context.Context, _ interface{}) (interface{}, error) {
+				tenant, _ := baggage.Get(ctx, "X-Tenant-ID")
+				return tenant, nil
+			},
+			Decode: nopDecoder,
+			Encode: func(_ context.Context, response interface{}) (json.RawMessage, error) {
+				return json.Marshal(response)
+			},
+		},
+	}
+	handler := jsonrpc.NewWebSocketServer(jsonrpc.NewServer(ecm,
+		jsonrpc.ServerBefore(jsonrpc.ExtractBaggage("X-Tenant-ID")),
+	))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := jsonrpc.NewWebSocketClient(wsURL(server),
+		jsonrpc.WebSocketClientBefore(jsonrpc.InjectBaggage("X-Tenant-ID")),
+	)
+	conn, err := client.Dial(baggage.Set(context.Background(), "X-Tenant-ID", "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	response, err := conn.Endpoint("tenant", nil, func(_ context.Context, res jsonrpc.Response) (interface{}, error) {
+		var tenant string
+		err := json.Unmarshal(res.Result, &tenant)
+		return tenant, err
+	})(context.Background(), struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme", response; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestWebSocketConcurrency(t *testing.T) {
+	var (
+		conns   = make(chan *jsonrpc.WebSocketConn, 1)
+		pinged  = make(chan struct{}, 1)
+		release = make(chan struct{})
+		ecm     = jsonrpc.EndpointCodecMap{
+			// "call" calls the client back, while the connection serves as
+			// many calls as allowed.
+			"call": jsonrpc.EndpointCodec{
+				Endpoint: func(ctx context.Context, _ interface{}) (interface{}, error) {
+					conn := <-conns
+					conns <- conn
+					return conn.Endpoint("ping", nil, nil)(ctx, struct{}{})
+				},
+				Decode: nopDecoder,
+				Encode: nopEncoder,
+			},
+		}
+		handler = jsonrpc.NewWebSocketServer(jsonrpc.NewServer(ecm),
+			jsonrpc.WebSocketConcurrency(1),
+			jsonrpc.WebSocketOnConnect(func(ctx context.Context, conn *jsonrpc.WebSocketConn) { conns <- conn }),
+		)
+		server = httptest.NewServer(handler)
+	)
+	defer server.Close()
+
+	client := jsonrpc.NewWebSocketClient(wsURL(server),
+		jsonrpc.WebSocketClientServer(jsonrpc.NewServer(jsonrpc.EndpointCodecMap{
+			"ping": jsonrpc.EndpointCodec{
+				Endpoint: func(context.Context, interface{}) (interface{}, error) {
+					pinged <- struct{}{}
+					<-release
+					return struct{}{}, nil
+				},
+				Decode: nopDecoder,
+				Encode: nopEncoder,
+			},
+		})),
+	)
+	conn, err := client.Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var (
+		call  = conn.Endpoint("call", nil, nil)
+		first = make(chan error, 1)
+	)
+	go func() {
+		_, err := call(ctx, struct{}{})
+		first <- err
+	}()
+	select {
+	case <-pinged:
+	case <-ctx.Done():
+		t.Fatal("the server didn't call the client back")
+	}
+
+	// The second call is over the limit, and rejected right away.
+	_, err = call(ctx, struct{}{})
+	var rpcerr jsonrpc.Error
+	if !errors.As(err, &rpcerr) || rpcerr.Code != jsonrpc.ServerBusyError {
+		t.Errorf("want server busy error, have %v", err)
+	}
+
+	// The first call still gets the response to its callback.
+	close(release)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	var calls int64
+	server := httptest.NewServer(jsonrpc.NewWebSocketServer(jsonrpc.NewServer(addECM(&calls)), jsonrpc.WebSocketReadLimit(128)))
+	defer server.Close()
+
+	conn, err := jsonrpc.NewWebSocketClient(wsURL(server)).Dial(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	add := conn.Endpoint("add", nil, decodeInt)
+	if _, err := add(context.Background(), []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Larger messages close the connection.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := add(ctx, make([]int, 100)); err != jsonrpc.ErrWebSocketClosed {
+		t.Errorf("want %v, have %v", jsonrpc.ErrWebSocketClosed, err)
+	}
+}
+
+func TestNewWebSocketServerNil(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic, have none")
+		}
+	}()
+	jsonrpc.NewWebSocketServer(nil)
+}
@@ -3,6 +3,8 @@ package http
 import (
 	"context"
 	"net/http"
+
+	"github.com/go-kit/kit/transport/baggage"
 )
 
 // RequestFunc may take information from an HTTP request and put it into a
@@ -67,6 +69,51 @@ func PopulateRequestContext(ctx context.Context, r *http.Request) context.Contex
 	return ctx
 }
 
+// ExtractBaggage returns a RequestFunc that copies the given headers of the
+// request, if present, into the baggage of the context. It's meant for
+// Servers.
+func ExtractBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		return baggage.Extract(ctx, keys, headerValue(r.Header))
+	}
+}
+
+// InjectBaggage returns a RequestFunc that sets the given keys of the baggage
+// of the context, if present, as headers of the request. It's meant for
+// Clients.
+func InjectBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, r *http.Request) context.Context {
+		baggage.Inject(ctx, keys, r.Header.Set)
+		return ctx
+	}
+}
+
+// InjectResponseBaggage returns a ServerResponseFunc that sets the given keys
+// of the baggage of the context, if present, as headers of the response.
+func InjectResponseBaggage(keys ...string) ServerResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter) context.Context {
+		baggage.Inject(ctx, keys, w.Header().Set)
+		return ctx
+	}
+}
+
+// ExtractResponseBaggage returns a ClientResponseFunc that copies the given
+// headers of the response, if present, into the baggage of the context.
+func ExtractResponseBaggage(keys ...string) ClientResponseFunc {
+	return func(ctx context.Context, r *http.Response) context.Context {
+		return baggage.Extract(ctx, keys, headerValue(r.Header))
+	}
+}
+
+func headerValue(h http.Header) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		if values := h.Values(key); len(values) > 0 {
+			return values[0], true
+		}
+		return "", false
+	}
+}
+
 type contextKey int
 
 const (
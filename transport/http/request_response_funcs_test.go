@@ -3,8 +3,10 @@ package http_test
 import (
 	"context"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	"github.com/go-kit/kit/transport/baggage"
 	httptransport "github.com/go-kit/kit/transport/http"
 )
 
@@ -28,3 +30,33 @@ func TestSetContentType(t *testing.T) {
 		t.Errorf("want %q, have %q", want, have)
 	}
 }
+
+func TestBaggage(t *testing.T) {
+	var (
+		ctx = baggage.Set(context.Background(), "X-Tenant-ID", "acme")
+		req = httptest.NewRequest("GET", "/", nil)
+		rec = httptest.NewRecorder()
+	)
+	ctx = baggage.Set(ctx, "X-Secret", "hunter2")
+
+	httptransport.InjectBaggage("X-Tenant-ID")(ctx, req)
+	if want, have := "acme", req.Header.Get("X-Tenant-Id"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if have := req.Header.Get("X-Secret"); have != "" {
+		t.Errorf("want no X-Secret header, have %q", have)
+	}
+
+	req.Header.Set("X-Secret", "hunter2")
+	extracted := httptransport.ExtractBaggage("x-tenant-id")(context.Background(), req)
+	if want, have := map[string]string{"x-tenant-id": "acme"}, baggage.FromContext(extracted); !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+
+	httptransport.InjectResponseBaggage("X-Tenant-ID")(extracted, rec)
+	resp := rec.Result()
+	extracted = httptransport.ExtractResponseBaggage("X-Tenant-ID")(context.Background(), resp)
+	if value, _ := baggage.Get(extracted, "X-Tenant-ID"); value != "acme" {
+		t.Errorf("want acme, have %q", value)
+	}
+}
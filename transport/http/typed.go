@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// TypedDecodeRequestFunc is a type-safe variant of DecodeRequestFunc.
+type TypedDecodeRequestFunc[Req any] func(context.Context, *http.Request) (request Req, err error)
+
+// Untyped converts the TypedDecodeRequestFunc to a DecodeRequestFunc.
+func (f TypedDecodeRequestFunc[Req]) Untyped() DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		return f(ctx, r)
+	}
+}
+
+// TypedEncodeRequestFunc is a type-safe variant of EncodeRequestFunc.
+type TypedEncodeRequestFunc[Req any] func(context.Context, *http.Request, Req) error
+
+// Untyped converts the TypedEncodeRequestFunc to an EncodeRequestFunc.
+func (f TypedEncodeRequestFunc[Req]) Untyped() EncodeRequestFunc {
+	return func(ctx context.Context, r *http.Request, request interface{}) error {
+		req, err := endpoint.Assert[Req](request)
+		if err != nil {
+			return err
+		}
+		return f(ctx, r, req)
+	}
+}
+
+// TypedEncodeResponseFunc is a type-safe variant of EncodeResponseFunc.
+type TypedEncodeResponseFunc[Resp any] func(context.Context, http.ResponseWriter, Resp) error
+
+// Untyped converts the TypedEncodeResponseFunc to an EncodeResponseFunc.
+func (f TypedEncodeResponseFunc[Resp]) Untyped() EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		resp, err := endpoint.Assert[Resp](response)
+		if err != nil {
+			return err
+		}
+		return f(ctx, w, resp)
+	}
+}
+
+// TypedDecodeResponseFunc is a type-safe variant of DecodeResponseFunc.
+type TypedDecodeResponseFunc[Resp any] func(context.Context, *http.Response) (response Resp, err error)
+
+// Untyped converts the TypedDecodeResponseFunc to a DecodeResponseFunc.
+func (f TypedDecodeResponseFunc[Resp]) Untyped() DecodeResponseFunc {
+	return func(ctx context.Context, r *http.Response) (interface{}, error) {
+		return f(ctx, r)
+	}
+}
+
+// NewTypedServer is like NewServer, but takes a Typed endpoint and type-safe
+// codecs. The returned Server is identical to one built with NewServer, and
+// accepts the same options.
+func NewTypedServer[Req, Resp any](
+	e endpoint.Typed[Req, Resp],
+	dec TypedDecodeRequestFunc[Req],
+	enc TypedEncodeResponseFunc[Resp],
+	options ...ServerOption,
+) *Server {
+	return NewServer(e.Endpoint(), dec.Untyped(), enc.Untyped(), options...)
+}
+
+// NewTypedClient is like NewClient, but takes type-safe codecs. Use
+// endpoint.FromEndpoint to obtain a Typed endpoint from the Client.
+func NewTypedClient[Req, Resp any](
+	method string,
+	tgt *url.URL,
+	enc TypedEncodeRequestFunc[Req],
+	dec TypedDecodeResponseFunc[Resp],
+	options ...ClientOption,
+) *Client {
+	return NewClient(method, tgt, enc.Untyped(), dec.Untyped(), options...)
+}
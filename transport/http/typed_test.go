@@ -0,0 +1,54 @@
+package http_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+func TestTypedServerAndClient(t *testing.T) {
+	handler := httptransport.NewTypedServer(
+		endpoint.Typed[int, int](func(_ context.Context, request int) (int, error) { return request * 2, nil }),
+		func(_ context.Context, r *http.Request) (int, error) { return strconv.Atoi(r.URL.Query().Get("n")) },
+		func(_ context.Context, w http.ResponseWriter, response int) error {
+			_, err := w.Write([]byte(strconv.Itoa(response)))
+			return err
+		},
+	)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := httptransport.NewTypedClient(
+		http.MethodGet,
+		u,
+		func(_ context.Context, r *http.Request, request int) error {
+			r.URL.RawQuery = "n=" + strconv.Itoa(request)
+			return nil
+		},
+		func(_ context.Context, r *http.Response) (int, error) {
+			buf, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return 0, err
+			}
+			return strconv.Atoi(strings.TrimSpace(string(buf)))
+		},
+	)
+
+	e := endpoint.FromEndpoint[int, int](client.Endpoint())
+	response, err := e(context.Background(), 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 42, response; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+}
@@ -77,7 +77,7 @@ func (p Publisher) Endpoint() endpoint.Endpoint {
 			ctx = f(ctx, &msg)
 		}
 
-		resp, err := p.publisher.RequestWithContext(ctx, msg.Subject, msg.Data)
+		resp, err := p.publisher.RequestMsgWithContext(ctx, &msg)
 		if err != nil {
 			return nil, err
 		}
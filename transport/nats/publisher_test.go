@@ -2,10 +2,13 @@ package nats_test
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/transport/baggage"
 	natstransport "github.com/go-kit/kit/transport/nats"
 	"github.com/nats-io/nats.go"
 )
@@ -229,6 +232,88 @@ func TestPublisherCancellation(t *testing.T) {
 	}
 }
 
+func TestPublisherBaggage(t *testing.T) {
+	s, c := newNATSConn(t)
+	defer func() { s.Shutdown(); s.WaitForShutdown() }()
+	defer c.Close()
+
+	subscriber := natstransport.NewSubscriber(
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			tenant, _ := baggage.Get(ctx, "X-Tenant-ID")
+			_, secret := baggage.Get(ctx, "X-Secret")
+			return TestResponse{String: tenant + " " + strconv.FormatBool(secret)}, nil
+		},
+		natstransport.NopRequestDecoder,
+		natstransport.EncodeJSONResponse,
+		natstransport.SubscriberBefore(natstransport.ExtractBaggage("x-tenant-id", "x-secret")),
+	)
+	sub, err := c.QueueSubscribe("natstransport.test", "natstransport", subscriber.ServeMsg(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	publisher := natstransport.NewPublisher(
+		c,
+		"natstransport.test",
+		natstransport.EncodeJSONRequest,
+		func(_ context.Context, msg *nats.Msg) (interface{}, error) {
+			var response TestResponse
+			err := json.Unmarshal(msg.Data, &response)
+			return response, err
+		},
+		natstransport.PublisherBefore(natstransport.InjectBaggage("X-Tenant-ID")),
+	)
+
+	ctx := baggage.Set(baggage.Set(context.Background(), "X-Tenant-ID", "acme"), "X-Secret", "hunter2")
+	res, err := publisher.Endpoint()(ctx, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme false", res.(TestResponse).String; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestPublisherResponseBaggage(t *testing.T) {
+	s, c := newNATSConn(t)
+	defer func() { s.Shutdown(); s.WaitForShutdown() }()
+	defer c.Close()
+
+	sub, err := c.QueueSubscribe("natstransport.test", "natstransport", func(msg *nats.Msg) {
+		reply := nats.NewMsg(msg.Reply)
+		reply.Header.Set("X-Tenant-ID", msg.Header.Get("X-Tenant-ID"))
+		reply.Data = []byte("{}")
+		msg.RespondMsg(reply)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	publisher := natstransport.NewPublisher(
+		c,
+		"natstransport.test",
+		natstransport.EncodeJSONRequest,
+		func(ctx context.Context, _ *nats.Msg) (interface{}, error) {
+			tenant, _ := baggage.Get(ctx, "X-Tenant-ID")
+			return tenant, nil
+		},
+		natstransport.PublisherBefore(natstransport.InjectBaggage("X-Tenant-ID")),
+		natstransport.PublisherAfter(natstransport.ExtractResponseBaggage("x-tenant-id")),
+	)
+
+	// The baggage makes the round trip, and comes back from the reply.
+	ctx := baggage.Set(context.Background(), "X-Tenant-ID", "acme")
+	res, err := publisher.Endpoint()(ctx, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "acme", res; want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
 func TestEncodeJSONRequest(t *testing.T) {
 	var data string
 
@@ -2,8 +2,11 @@ package nats
 
 import (
 	"context"
+	"strings"
 
 	"github.com/nats-io/nats.go"
+
+	"github.com/go-kit/kit/transport/baggage"
 )
 
 // RequestFunc may take information from a publisher request and put it into a
@@ -20,3 +23,49 @@ type SubscriberResponseFunc func(context.Context, *nats.Conn) context.Context
 // response available for consumption. ClientResponseFuncs are only executed in
 // clients, after a request has been made, but prior to it being decoded.
 type PublisherResponseFunc func(context.Context, *nats.Msg) context.Context
+
+// ExtractBaggage returns a RequestFunc that copies the given headers of the
+// message, if present, into the baggage of the context. It's meant for
+// Subscribers. Header names are matched case-insensitively.
+func ExtractBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, msg *nats.Msg) context.Context {
+		return baggage.Extract(ctx, keys, headerValue(msg.Header))
+	}
+}
+
+// InjectBaggage returns a RequestFunc that sets the given keys of the baggage
+// of the context, if present, as headers of the message. It's meant for
+// Publishers. Headers require NATS server 2.2 or later.
+func InjectBaggage(keys ...string) RequestFunc {
+	return func(ctx context.Context, msg *nats.Msg) context.Context {
+		baggage.Inject(ctx, keys, func(key, value string) {
+			if msg.Header == nil {
+				msg.Header = nats.Header{}
+			}
+			msg.Header.Set(key, value)
+		})
+		return ctx
+	}
+}
+
+// ExtractResponseBaggage returns a PublisherResponseFunc that copies the given
+// headers of the reply, if present, into the baggage of the context. Header
+// names are matched case-insensitively. Subscribers publish their replies with
+// their EncodeResponseFunc, so that's where they set the headers, e.g. with
+// baggage.Inject and nats.Conn.PublishMsg.
+func ExtractResponseBaggage(keys ...string) PublisherResponseFunc {
+	return func(ctx context.Context, msg *nats.Msg) context.Context {
+		return baggage.Extract(ctx, keys, headerValue(msg.Header))
+	}
+}
+
+func headerValue(h nats.Header) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		for k, values := range h {
+			if len(values) > 0 && strings.EqualFold(k, key) {
+				return values[0], true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,80 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/nats-io/nats.go"
+)
+
+// TypedDecodeRequestFunc is a type-safe variant of DecodeRequestFunc.
+type TypedDecodeRequestFunc[Req any] func(context.Context, *nats.Msg) (request Req, err error)
+
+// Untyped converts the TypedDecodeRequestFunc to a DecodeRequestFunc.
+func (f TypedDecodeRequestFunc[Req]) Untyped() DecodeRequestFunc {
+	return func(ctx context.Context, msg *nats.Msg) (interface{}, error) {
+		return f(ctx, msg)
+	}
+}
+
+// TypedEncodeRequestFunc is a type-safe variant of EncodeRequestFunc.
+type TypedEncodeRequestFunc[Req any] func(context.Context, *nats.Msg, Req) error
+
+// Untyped converts the TypedEncodeRequestFunc to an EncodeRequestFunc.
+func (f TypedEncodeRequestFunc[Req]) Untyped() EncodeRequestFunc {
+	return func(ctx context.Context, msg *nats.Msg, request interface{}) error {
+		req, err := endpoint.Assert[Req](request)
+		if err != nil {
+			return err
+		}
+		return f(ctx, msg, req)
+	}
+}
+
+// TypedEncodeResponseFunc is a type-safe variant of EncodeResponseFunc.
+type TypedEncodeResponseFunc[Resp any] func(context.Context, string, *nats.Conn, Resp) error
+
+// Untyped converts the TypedEncodeResponseFunc to an EncodeResponseFunc.
+func (f TypedEncodeResponseFunc[Resp]) Untyped() EncodeResponseFunc {
+	return func(ctx context.Context, reply string, nc *nats.Conn, response interface{}) error {
+		resp, err := endpoint.Assert[Resp](response)
+		if err != nil {
+			return err
+		}
+		return f(ctx, reply, nc, resp)
+	}
+}
+
+// TypedDecodeResponseFunc is a type-safe variant of DecodeResponseFunc.
+type TypedDecodeResponseFunc[Resp any] func(context.Context, *nats.Msg) (response Resp, err error)
+
+// Untyped converts the TypedDecodeResponseFunc to a DecodeResponseFunc.
+func (f TypedDecodeResponseFunc[Resp]) Untyped() DecodeResponseFunc {
+	return func(ctx context.Context, msg *nats.Msg) (interface{}, error) {
+		return f(ctx, msg)
+	}
+}
+
+// NewTypedSubscriber is like NewSubscriber, but takes a Typed endpoint and
+// type-safe codecs. The returned Subscriber is identical to one built with
+// NewSubscriber, and accepts the same options.
+func NewTypedSubscriber[Req, Resp any](
+	e endpoint.Typed[Req, Resp],
+	dec TypedDecodeRequestFunc[Req],
+	enc TypedEncodeResponseFunc[Resp],
+	options ...SubscriberOption,
+) *Subscriber {
+	return NewSubscriber(e.Endpoint(), dec.Untyped(), enc.Untyped(), options...)
+}
+
+// NewTypedPublisher is like NewPublisher, but takes type-safe codecs. Use
+// endpoint.FromEndpoint to obtain a Typed endpoint from the Publisher.
+func NewTypedPublisher[Req, Resp any](
+	publisher *nats.Conn,
+	subject string,
+	enc TypedEncodeRequestFunc[Req],
+	dec TypedDecodeResponseFunc[Resp],
+	options ...PublisherOption,
+) *Publisher {
+	return NewPublisher(publisher, subject, enc.Untyped(), dec.Untyped(), options...)
+}
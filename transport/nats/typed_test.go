@@ -0,0 +1,55 @@
+package nats_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/go-kit/kit/endpoint"
+	natstransport "github.com/go-kit/kit/transport/nats"
+)
+
+func TestTypedSubscriberAndPublisher(t *testing.T) {
+	s, c := newNATSConn(t)
+	defer func() { s.Shutdown(); s.WaitForShutdown() }()
+	defer c.Close()
+
+	handler := natstransport.NewTypedSubscriber(
+		endpoint.Typed[int, int](func(_ context.Context, request int) (int, error) { return request * 2, nil }),
+		func(_ context.Context, msg *nats.Msg) (int, error) { return strconv.Atoi(string(msg.Data)) },
+		func(_ context.Context, reply string, nc *nats.Conn, response int) error {
+			return nc.Publish(reply, []byte(strconv.Itoa(response)))
+		},
+	)
+	sub, err := c.QueueSubscribe("natstransport.test", "natstransport", handler.ServeMsg(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	publisher := natstransport.NewTypedPublisher(
+		c,
+		"natstransport.test",
+		func(_ context.Context, msg *nats.Msg, request int) error {
+			msg.Data = []byte(strconv.Itoa(request))
+			return nil
+		},
+		func(_ context.Context, msg *nats.Msg) (int, error) { return strconv.Atoi(string(msg.Data)) },
+	)
+
+	e := endpoint.FromEndpoint[int, int](publisher.Endpoint())
+	response, err := e(context.Background(), 21)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 42, response; want != have {
+		t.Errorf("want %d, have %d", want, have)
+	}
+
+	// The untyped endpoint rejects requests of the wrong type.
+	if _, err := publisher.Endpoint()(context.Background(), "21"); err == nil {
+		t.Error("want error, have none")
+	}
+}